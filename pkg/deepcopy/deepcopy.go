@@ -2,9 +2,18 @@
 //
 // It is extended version of https://gist.github.com/hvoecking/10772475.
 //
-// TranslateFn can be used to modify value on copying.
+// TranslateFn can be used to modify value on copying, TranslateFnWithParent receives also the parent of the value.
+//
+// CopyWithOptions can be used to configure the copy operation, see Option.
+//
+// Nil maps and slices are copied as nil, empty maps and slices are copied as empty, non-nil values.
+//
+// Channels and functions are copied by reference, the same as on assignment, see WithErrorOnUncopyable option.
+//
+// Struct fields can be copied by assignment or skipped, see FieldTag.
 //
 // CustomDeepCopyMethod can be defined on a type, for example, to copy unexported fields.
+// Well-known standard library types, for example time.Time, are copied by value, see WithCopiers.
 // See "github.com/keboola/go-utils/pkg/orderedmap" package for example of CustomDeepCopyMethod.
 package deepcopy
 
@@ -14,11 +23,44 @@ import (
 )
 
 // CustomDeepCopyMethod is name of the method that handles deep copy for the type.
+//
+//	func (v T) HandleDeepCopy(callback TranslateFn, path Path, ctx *Context) (T, CloneFn)
+//
+// Nested values should be copied by CopyTranslateContext, so the options of the copy operation apply to them.
+// The third parameter can be also VisitedPtrMap, then the nested values copied by CopyTranslateSteps use the default options.
 const CustomDeepCopyMethod = "HandleDeepCopy"
 
+// FieldTag is name of the struct tag that modifies copying of the field.
+//
+//	`deepcopy:"shallow"` - the field is copied by assignment, for example a logger or a client.
+//	`deepcopy:"skip"`    - the field is left zero, it can be also unexported.
+const FieldTag = "deepcopy"
+
+const (
+	FieldTagShallow = "shallow"
+	FieldTagSkip    = "skip"
+)
+
 // TranslateFn is custom translate function to modify values on copying.
 type TranslateFn func(original, clone reflect.Value, path Path)
 
+// TranslateFnWithParent is custom translate function to modify values on copying, see CopyTranslateParent.
+// The parent is the original container of the value: a struct, a map or a slice.
+// Pointers and interfaces are skipped, so the value and the pointer to it have the same parent.
+// The parent of the root value is invalid reflect.Value.
+type TranslateFnWithParent func(original, clone reflect.Value, path Path, parent reflect.Value)
+
+// Stats contains counts of the values visited by CopyWithStats.
+// Interfaces are not counted, the value inside is counted. A value with CustomDeepCopyMethod is counted by its kind.
+type Stats struct {
+	Structs  int
+	Slices   int
+	Maps     int
+	Pointers int
+	// Leaves are values without nested values, for example strings, numbers, channels and functions.
+	Leaves int
+}
+
 // CloneFn is custom implementation of deepcopy for a type, it is returned from CustomDeepCopyMethod.
 type CloneFn func(clone reflect.Value)
 
@@ -27,19 +69,96 @@ type CloneFn func(clone reflect.Value)
 // Then, in the cloned value AC, there will be 3x pointer to the cloned value BC.
 type VisitedPtrMap map[uintptr]*reflect.Value
 
+// Context of a copy operation, it is passed to CustomDeepCopyMethod, see CopyTranslateContext.
+type Context struct {
+	visited VisitedPtrMap
+	config  *config
+}
+
 // Copy makes deep copy of the value.
 func Copy(value any) any {
 	return CopyTranslate(value, nil)
 }
 
+// CopyWithStats makes deep copy of the value and returns counts of the visited values, see Stats.
+func CopyWithStats(value any) (any, Stats) {
+	c := &config{stats: &Stats{}}
+	clone := copyWithConfig(value, nil, Path{}, make(VisitedPtrMap), c)
+	return clone, *c.stats
+}
+
+// CopyAs makes deep copy of the value and converts it to the type T.
+// An error is returned if the copy is not of the type T, nil value is converted to the zero value.
+func CopyAs[T any](value any) (T, error) {
+	var out T
+	if value == nil {
+		return out, nil
+	}
+	out, ok := Copy(value).(T)
+	if !ok {
+		return out, fmt.Errorf(`deepcopy value of type "%T" cannot be converted to "%s"`, value, reflect.TypeOf(&out).Elem())
+	}
+	return out, nil
+}
+
+// Copier makes deep copies of related values, a pointer shared between the values is cloned only once.
+// For example, if the values A and B contain the same *Bar, the clones of A and B contain the same cloned *Bar.
+//
+// The Copier keeps references to all copied pointers and their clones, so they cannot be garbage collected while the Copier is referenced.
+// The VisitedPtrMap is keyed by addresses, so the references also prevent reuse of an address by a new value.
+// Use a new Copier for each group of related values.
+type Copier struct {
+	visited VisitedPtrMap
+	config  *config
+}
+
+// NewCopier creates Copier with an empty VisitedPtrMap.
+func NewCopier() *Copier {
+	return &Copier{visited: make(VisitedPtrMap), config: &config{pinVisited: true}}
+}
+
+// Copy makes deep copy of the value, pointers visited by previous copies are reused.
+func (c *Copier) Copy(value any) any {
+	return copyWithConfig(value, nil, Path{}, c.visited, c.config)
+}
+
 // CopyTranslate makes deep copy of the value, each value is translated by TranslateFn.
 func CopyTranslate(value any, callback TranslateFn) any {
 	return CopyTranslateSteps(value, callback, Path{}, make(VisitedPtrMap))
 }
 
+// CopyTranslateUnder makes deep copy of the value, only values nested under the prefix are translated by TranslateFn.
+func CopyTranslateUnder(value any, prefix Path, callback TranslateFn) any {
+	return CopyTranslate(value, func(original, clone reflect.Value, path Path) {
+		if len(path) > len(prefix) && path.HasPrefix(prefix) {
+			callback(original, clone, path)
+		}
+	})
+}
+
+// CopyTranslateParent makes deep copy of the value, each value is translated by TranslateFnWithParent.
+// Unlike TranslateFn, the callback receives the parent of the value, for example to modify a value according to a sibling field.
+func CopyTranslateParent(value any, callback TranslateFnWithParent) any {
+	return copyWithConfig(value, nil, Path{}, make(VisitedPtrMap), &config{parentCallback: callback})
+}
+
 // CopyTranslateSteps makes deep copy of the value, each value is translated by TranslateFn.
 // VisitedPtrMap allows you to connect copy to another copy operation and reuse pointers.
 func CopyTranslateSteps(value any, callback TranslateFn, path Path, visited VisitedPtrMap) any {
+	return copyWithConfig(value, callback, path, visited, &config{})
+}
+
+// CopyTranslateContext makes deep copy of the value, each value is translated by TranslateFn.
+// The Context is received by CustomDeepCopyMethod, the nested copy uses the options and the VisitedPtrMap of the copy operation.
+// Nil Context starts a new copy operation with the default options.
+func CopyTranslateContext(value any, callback TranslateFn, path Path, ctx *Context) any {
+	if ctx == nil {
+		return CopyTranslateSteps(value, callback, path, make(VisitedPtrMap))
+	}
+	return copyWithConfig(value, callback, path, ctx.visited, ctx.config)
+}
+
+func copyWithConfig(value any, callback TranslateFn, path Path, visited VisitedPtrMap, c *config) any {
 	if value == nil {
 		return nil
 	}
@@ -47,13 +166,41 @@ func CopyTranslateSteps(value any, callback TranslateFn, path Path, visited Visi
 	// Wrap the original in a reflect.Value
 	original := reflect.ValueOf(value)
 	clone := reflect.New(original.Type()).Elem()
-	translateRecursive(clone, original, callback, path, visited)
+	translateRecursive(clone, original, callback, path, visited, c, c.methodParent)
 
 	// Remove the reflection wrapper
 	return clone.Interface()
 }
 
-func translateRecursive(clone, original reflect.Value, callback TranslateFn, path Path, visitedPtr VisitedPtrMap) {
+// CopyTo makes deep copy of the src value into the dst, dst must be a non-nil pointer to the src type.
+func CopyTo(dst, src any) error {
+	dstPtr := reflect.ValueOf(dst)
+	if dstPtr.Kind() != reflect.Ptr || dstPtr.IsNil() {
+		return fmt.Errorf(`dst must be a non-nil pointer, found "%T"`, dst)
+	}
+	if src == nil {
+		return fmt.Errorf(`src cannot be nil`)
+	}
+
+	original := reflect.ValueOf(src)
+	if dstPtr.Elem().Type() != original.Type() {
+		return fmt.Errorf(`dst must be a pointer to "%s", found "%T"`, original.Type(), dst)
+	}
+
+	translateRecursive(dstPtr.Elem(), original, nil, Path{}, make(VisitedPtrMap), &config{}, reflect.Value{})
+	return nil
+}
+
+func translateRecursive(clone, original reflect.Value, callback TranslateFn, path Path, visitedPtr VisitedPtrMap, c *config, parent reflect.Value) {
+	c.checkDepth(path)
+	c.count(original.Kind())
+
+	// Fast path, a string is immutable, so it can be assigned without the reflection machinery below
+	if c.canAssignString(original, callback) {
+		clone.Set(original)
+		return
+	}
+
 	originalType := original.Type()
 	cloneMethod, cloneMethodFound := originalType.MethodByName(CustomDeepCopyMethod)
 	kind := original.Kind()
@@ -68,15 +215,32 @@ func translateRecursive(clone, original reflect.Value, callback TranslateFn, pat
 		}
 		// Cloned value not found, continue
 		visitedPtr[ptr] = &clone
+		c.pin(original)
 	}
 
+	copier, copierFound := c.copier(originalType)
+
 	switch {
+	// Use custom copy function if is registered
+	case copierFound:
+		value := copier(original)
+		if !value.IsValid() || !value.Type().AssignableTo(clone.Type()) {
+			c.fail(fmt.Errorf("deepcopy custom copier for \"%s\" returned invalid value:\n  path: %s", originalType, path.String()))
+		}
+		clone.Set(value)
 	// Use CustomDeepCopyMethod method if is present
 	case cloneMethodFound && cloneMethod.Type.Out(0).String() == originalType.String():
+		// Nested values copied by the method have the original value as the parent
+		defer c.setMethodParent(original)()
+		// The method receives the Context, or only the VisitedPtrMap
+		ctx := reflect.ValueOf(visitedPtr)
+		if cloneMethod.Type.NumIn() == 4 && cloneMethod.Type.In(3) == reflect.TypeOf(&Context{}) {
+			ctx = reflect.ValueOf(&Context{visited: visitedPtr, config: c})
+		}
 		values := original.MethodByName(CustomDeepCopyMethod).Call([]reflect.Value{
 			reflect.ValueOf(callback),
 			reflect.ValueOf(path.Add(TypeStep{CurrentType: originalType.String()})),
-			reflect.ValueOf(visitedPtr),
+			ctx,
 		})
 		if len(values) != 2 {
 			panic(fmt.Errorf(`expected two return value from %s.%s, got %d`, cloneMethod.PkgPath, cloneMethod.Name, len(values)))
@@ -98,7 +262,7 @@ func translateRecursive(clone, original reflect.Value, callback TranslateFn, pat
 			clone.Set(reflect.New(originalValue.Type()))
 			// Unwrap the newly created pointer
 			path := path.Add(PointerStep{})
-			translateRecursive(clone.Elem(), originalValue, callback, path, visitedPtr)
+			translateRecursive(clone.Elem(), originalValue, callback, path, visitedPtr, c, parent)
 		}
 
 	// If it is an interface (which is very similar to a pointer), do basically the
@@ -115,7 +279,7 @@ func translateRecursive(clone, original reflect.Value, callback TranslateFn, pat
 			t := originalValue.Type()
 			cloneValue := reflect.New(t).Elem()
 			path := path.Add(InterfaceStep{TargetType: t})
-			translateRecursive(cloneValue, originalValue, callback, path, visitedPtr)
+			translateRecursive(cloneValue, originalValue, callback, path, visitedPtr, c, parent)
 			clone.Set(cloneValue)
 		}
 
@@ -123,44 +287,92 @@ func translateRecursive(clone, original reflect.Value, callback TranslateFn, pat
 	case kind == reflect.Struct:
 		t := originalType
 		for i := 0; i < original.NumField(); i++ {
-			path := path.Add(StructFieldStep{CurrentType: originalType, Field: t.Field(i).Name})
+			field := t.Field(i)
 			cloneField := clone.Field(i)
+			originalField := original.Field(i)
+			tag := field.Tag.Get(FieldTag)
+			if tag == FieldTagSkip {
+				// Skipped field is left zero
+				if cloneField.CanSet() {
+					cloneField.Set(reflect.Zero(field.Type))
+				}
+				continue
+			}
 			if !cloneField.CanSet() {
-				panic(fmt.Errorf("deepcopy found unexported field:\n  path: %s\n  value: %#v", path.String(), original.Interface()))
+				path := path.Add(StructFieldStep{CurrentType: originalType, Field: field.Name})
+				c.fail(fmt.Errorf("deepcopy found unexported field:\n  path: %s\n  value: %#v", path.String(), original.Interface()))
+			}
+			if tag == FieldTagShallow || c.canAssignString(originalField, callback) {
+				// Shallow field and string field are copied by assignment
+				cloneField.Set(originalField)
+				continue
 			}
-			translateRecursive(cloneField, original.Field(i), callback, path, visitedPtr)
+			path := path.Add(StructFieldStep{CurrentType: originalType, Field: field.Name})
+			translateRecursive(cloneField, originalField, callback, path, visitedPtr, c, original)
 		}
 
 	// If it is a slice we create a new slice and translate each element
 	case kind == reflect.Slice:
 		if !original.IsNil() {
+			// Slice contains itself, use the clone that is in progress
+			if v, found := c.inProgress(original); found {
+				clone.Set(*v)
+				break
+			}
 			clone.Set(reflect.MakeSlice(originalType, original.Len(), original.Cap()))
+			// Fast path, elements without nested references can be copied at once
+			if callback == nil && c.canCopyByValue(originalType.Elem()) {
+				reflect.Copy(clone, original)
+				break
+			}
+			done := c.startProgress(original, &clone)
 			for i := 0; i < original.Len(); i++ {
 				path := path.Add(SliceIndexStep{Index: i})
-				translateRecursive(clone.Index(i), original.Index(i), callback, path, visitedPtr)
+				translateRecursive(clone.Index(i), original.Index(i), callback, path, visitedPtr, c, original)
 			}
+			done()
 		}
 
 	// If it is a map we create a new map and translate each value
 	case kind == reflect.Map:
 		if !original.IsNil() {
+			// Map contains itself, use the clone that is in progress
+			if v, found := c.inProgress(original); found {
+				clone.Set(*v)
+				break
+			}
 			clone.Set(reflect.MakeMap(originalType))
+			done := c.startProgress(original, &clone)
 			for _, originalKey := range original.MapKeys() {
-				// Clone key
-				cloneKey := reflect.New(originalKey.Type()).Elem()
-				keySteps := path.Add(MapKeyValueStep{Key: originalKey.Interface()})
-				translateRecursive(cloneKey, originalKey, callback, keySteps, visitedPtr)
+				// Clone key, a string key can be used directly
+				cloneKey := originalKey
+				if !c.canAssignString(originalKey, callback) {
+					cloneKey = reflect.New(originalKey.Type()).Elem()
+					keySteps := path.Add(MapKeyValueStep{Key: originalKey.Interface()})
+					translateRecursive(cloneKey, originalKey, callback, keySteps, visitedPtr, c, original)
+				}
 
 				// New gives us a pointer, but again we want the value
 				originalValue := original.MapIndex(originalKey)
-				cloneValue := reflect.New(originalValue.Type()).Elem()
-				path := path.Add(MapKeyStep{Key: originalKey.Interface()})
-				translateRecursive(cloneValue, originalValue, callback, path, visitedPtr)
+				cloneValue := originalValue
+				if !c.canAssignString(originalValue, callback) {
+					cloneValue = reflect.New(originalValue.Type()).Elem()
+					path := path.Add(MapKeyStep{Key: originalKey.Interface()})
+					translateRecursive(cloneValue, originalValue, callback, path, visitedPtr, c, original)
+				}
 
 				clone.SetMapIndex(cloneKey, cloneValue)
 			}
+			done()
 		}
 
+	// Channels and functions cannot be copied, they are copied by reference, the same as on assignment
+	case kind == reflect.Chan || kind == reflect.Func:
+		if c.errorOnUncopyable && !original.IsNil() {
+			c.fail(fmt.Errorf("deepcopy found uncopyable %s:\n  path: %s", kind, path.String()))
+		}
+		clone.Set(original)
+
 	// And everything else will simply be taken from the original
 	default:
 		clone.Set(original)
@@ -170,4 +382,7 @@ func translateRecursive(clone, original reflect.Value, callback TranslateFn, pat
 	if callback != nil {
 		callback(original, clone, path.Add(TypeStep{CurrentType: kind.String()}))
 	}
+	if c.parentCallback != nil {
+		c.parentCallback(original, clone, path.Add(TypeStep{CurrentType: kind.String()}), parent)
+	}
 }
@@ -0,0 +1,254 @@
+package deepcopy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Option for the CopyWithOptions function.
+type Option func(c *config)
+
+// CopierFn is custom copy function for a type, see Copiers.
+type CopierFn func(original reflect.Value) reflect.Value
+
+// Copiers is a registry of custom copy functions.
+// It can be used for types that cannot define CustomDeepCopyMethod, for example third-party types with unexported fields.
+type Copiers map[reflect.Type]CopierFn
+
+// defaultCopiers contains copy functions of well-known standard library types, Copiers set by WithCopiers take precedence.
+// The types with unexported fields are copied by value, without the descent to the fields.
+var defaultCopiers = Copiers{ // nolint gochecknoglobals
+	reflect.TypeOf(time.Time{}): copyByValue,
+	reflect.TypeOf(url.URL{}):   copyByValue, // the *url.Userinfo is immutable, so it can be shared
+	reflect.TypeOf(net.IP{}): func(original reflect.Value) reflect.Value {
+		return reflect.ValueOf(slices.Clone(original.Interface().(net.IP)))
+	},
+}
+
+// config for the CopyWithOptions function.
+type config struct {
+	callback TranslateFn
+	// parentCallback is set by CopyTranslateParent.
+	parentCallback TranslateFnWithParent
+	// stats is set by CopyWithStats.
+	stats *Stats
+	// methodParent is the value whose CustomDeepCopyMethod is in progress, it is the parent of the values copied by the method.
+	methodParent reflect.Value
+	copiers      Copiers
+	maxDepth     int
+	// errorOnUncopyable is true if channels and functions should not be copied by reference.
+	errorOnUncopyable bool
+	// returnErr is true if the errors should be returned from CopyWithOptions, instead of a panic.
+	returnErr bool
+	// inProgressMap contains slices and maps that are being copied, it is used to detect cycles.
+	inProgressMap map[containerKey]*reflect.Value
+	// pinVisited is true if the visited pointers should be kept alive, it is set by Copier.
+	// The VisitedPtrMap outlives the copy operation, so an address of a collected value could be reused by a new value.
+	pinVisited bool
+	pinned     []reflect.Value
+}
+
+// containerKey identifies a slice or a map by the underlying data pointer.
+type containerKey struct {
+	ptr    uintptr
+	length int
+	typ    reflect.Type
+}
+
+// copyError is an error returned from CopyWithOptions.
+type copyError struct {
+	err error
+}
+
+// WithTranslate sets TranslateFn to modify values on copying.
+func WithTranslate(callback TranslateFn) Option {
+	return func(c *config) {
+		c.callback = callback
+	}
+}
+
+// WithCopiers sets custom copy functions for types, they take precedence over CustomDeepCopyMethod.
+// They also take precedence over the default copy functions of time.Time, url.URL and net.IP.
+func WithCopiers(copiers Copiers) Option {
+	return func(c *config) {
+		c.copiers = copiers
+	}
+}
+
+// WithErrorOnUncopyable causes an error if a non-nil channel or function is found.
+// By default, channels and functions are copied by reference, the same as on assignment.
+func WithErrorOnUncopyable() Option {
+	return func(c *config) {
+		c.errorOnUncopyable = true
+	}
+}
+
+// WithMaxDepth sets maximum depth of the copied value, the depth is length of the Path to a nested value.
+// If the depth is exceeded, an error is returned. By default, the depth is unlimited.
+func WithMaxDepth(depth int) Option {
+	return func(c *config) {
+		c.maxDepth = depth
+	}
+}
+
+// CopyWithOptions makes deep copy of the value, the copy operation can be modified by options.
+// Unlike the Copy function, errors are returned instead of a panic.
+func CopyWithOptions(value any, opts ...Option) (clone any, err error) {
+	c := &config{returnErr: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if v, ok := r.(copyError); ok {
+				clone, err = nil, v.err
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	return copyWithConfig(value, c.callback, Path{}, make(VisitedPtrMap), c), nil
+}
+
+// RegisterCopier registers custom copy function for the type.
+func (v Copiers) RegisterCopier(t reflect.Type, fn CopierFn) {
+	v[t] = fn
+}
+
+// copyByValue is CopierFn for immutable values, the value is copied by assignment.
+func copyByValue(original reflect.Value) reflect.Value {
+	return original
+}
+
+// copier returns custom copy function for the type, from the Copiers option or from the defaultCopiers.
+func (c *config) copier(t reflect.Type) (CopierFn, bool) {
+	if fn, found := c.copiers[t]; found {
+		return fn, true
+	}
+	fn, found := defaultCopiers[t]
+	return fn, found
+}
+
+// fail stops the copy operation, the error is returned from CopyWithOptions or a panic is raised.
+func (c *config) fail(err error) {
+	if c.returnErr {
+		panic(copyError{err: err})
+	}
+	panic(err)
+}
+
+// inProgress returns clone of the slice or map, if the value is being copied, so the value contains itself.
+func (c *config) inProgress(original reflect.Value) (*reflect.Value, bool) {
+	v, found := c.inProgressMap[newContainerKey(original)]
+	return v, found
+}
+
+// startProgress marks the slice or map as being copied, the returned function must be called when the copy is done.
+func (c *config) startProgress(original reflect.Value, clone *reflect.Value) func() {
+	if c.inProgressMap == nil {
+		c.inProgressMap = make(map[containerKey]*reflect.Value)
+	}
+	key := newContainerKey(original)
+	c.inProgressMap[key] = clone
+	return func() {
+		delete(c.inProgressMap, key)
+	}
+}
+
+func newContainerKey(v reflect.Value) containerKey {
+	key := containerKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.length = v.Len()
+	}
+	return key
+}
+
+// canCopyByValue returns true if a value of the type can be copied by assignment with the same result as the deep copy.
+func (c *config) canCopyByValue(t reflect.Type) bool {
+	// Options are applied to each nested value
+	if c.maxDepth > 0 || len(c.copiers) > 0 || c.parentCallback != nil || c.stats != nil {
+		return false
+	}
+	return isScalarType(t)
+}
+
+// canAssignString returns true if the value is a string, which is immutable, so it can be copied by assignment.
+// The translate callback must be called for each value, so the assignment is not possible if the callback is set.
+func (c *config) canAssignString(v reflect.Value, callback TranslateFn) bool {
+	return callback == nil && v.Kind() == reflect.String && c.canCopyByValue(v.Type())
+}
+
+// isScalarType returns true for a scalar type or a struct with exported scalar fields.
+// A struct with a field tagged by FieldTag is not scalar, the tag must be handled for each field.
+func isScalarType(t reflect.Type) bool {
+	if _, found := t.MethodByName(CustomDeepCopyMethod); found {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128,
+		reflect.String:
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if _, tagged := field.Tag.Lookup(FieldTag); tagged || !field.IsExported() || !isScalarType(field.Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// setMethodParent sets the parent of the values copied by CustomDeepCopyMethod, the returned function restores the previous parent.
+func (c *config) setMethodParent(parent reflect.Value) func() {
+	prev := c.methodParent
+	c.methodParent = parent
+	return func() {
+		c.methodParent = prev
+	}
+}
+
+// pin keeps reference to the visited pointer, if it is enabled by the pinVisited field.
+func (c *config) pin(original reflect.Value) {
+	if c.pinVisited {
+		c.pinned = append(c.pinned, original)
+	}
+}
+
+// count increments counter of the kind, if the stats are enabled.
+func (c *config) count(kind reflect.Kind) {
+	if c.stats == nil {
+		return
+	}
+	switch kind {
+	case reflect.Struct:
+		c.stats.Structs++
+	case reflect.Slice:
+		c.stats.Slices++
+	case reflect.Map:
+		c.stats.Maps++
+	case reflect.Ptr:
+		c.stats.Pointers++
+	case reflect.Interface:
+		// The value inside is counted
+	default:
+		c.stats.Leaves++
+	}
+}
+
+func (c *config) checkDepth(path Path) {
+	if c.maxDepth > 0 && len(path) > c.maxDepth {
+		c.fail(fmt.Errorf("deepcopy exceeded max depth %d:\n  path: %s", c.maxDepth, path.String()))
+	}
+}
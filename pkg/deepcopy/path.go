@@ -18,6 +18,19 @@ func (s Path) Add(step fmt.Stringer) Path {
 	return out
 }
 
+// HasPrefix returns true if the path starts with all steps from the prefix.
+func (s Path) HasPrefix(prefix Path) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, step := range prefix {
+		if s[i].String() != step.String() {
+			return false
+		}
+	}
+	return true
+}
+
 func (s Path) String() string {
 	var out []string
 	for _, item := range s {
@@ -0,0 +1,20 @@
+package orderedmap
+
+// SetComment sets comment of the key, an empty comment removes the comment.
+// The comment is written before the key by MarshalJSONC and MarshalYAML, it is removed together with the key.
+func (o *OrderedMap) SetComment(key, comment string) {
+	key = o.storedKey(key)
+	if comment == "" {
+		delete(o.comments, key)
+		return
+	}
+	if o.comments == nil {
+		o.comments = make(map[string]string)
+	}
+	o.comments[key] = comment
+}
+
+// Comment returns comment of the key, or an empty string if the key has no comment.
+func (o *OrderedMap) Comment(key string) string {
+	return o.comments[o.storedKey(key)]
+}
@@ -0,0 +1,96 @@
+package orderedmap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestOrderedMap_Comment(t *testing.T) {
+	t.Parallel()
+	o := New()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	assert.Equal(t, "", o.Comment("a"))
+
+	o.SetComment("a", "comment")
+	assert.Equal(t, "comment", o.Comment("a"))
+
+	// Empty comment removes the comment
+	o.SetComment("a", "")
+	assert.Equal(t, "", o.Comment("a"))
+
+	// Comment is removed together with the key
+	o.SetComment("b", "comment")
+	o.Delete("b")
+	o.Set("b", 2)
+	assert.Equal(t, "", o.Comment("b"))
+
+	// Comment is copied by Clone
+	o.SetComment("a", "comment")
+	assert.Equal(t, "comment", o.Clone().Comment("a"))
+}
+
+func TestOrderedMap_Comment_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+	o := NewCaseInsensitive()
+	o.Set("Key", 1)
+	o.SetComment("KEY", "comment")
+	assert.Equal(t, "comment", o.Comment("key"))
+}
+
+func TestOrderedMap_MarshalJSONC(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("x", 1)
+	nested.SetComment("x", "first line\nsecond line")
+	o := New()
+	o.Set("name", "value")
+	o.Set("enabled", true)
+	o.Set("nested", nested)
+	o.SetComment("enabled", "head comment")
+
+	expected := `
+{
+  "name": "value",
+  // head comment
+  "enabled": true,
+  "nested": {
+    // first line
+    // second line
+    "x": 1
+  }
+}
+`
+	b, err := o.MarshalJSONC()
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimLeft(expected, "\n"), string(b)+"\n")
+
+	// Comments are not written to JSON
+	b, err = o.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"value","enabled":true,"nested":{"x":1}}`, string(b))
+}
+
+func TestOrderedMap_MarshalYAML_Comment(t *testing.T) {
+	t.Parallel()
+	o := New()
+	o.Set("a", 1)
+	o.Set("b", &yaml.Node{Kind: yaml.ScalarNode, Value: "2", HeadComment: "value comment"})
+	o.SetComment("a", "key comment")
+	o.SetComment("b", "key comment")
+
+	expected := `
+# key comment
+a: 1
+# key comment
+# value comment
+b: 2
+`
+	var buf bytes.Buffer
+	assert.NoError(t, yaml.NewEncoder(&buf).Encode(o))
+	assert.Equal(t, strings.TrimLeft(expected, "\n"), buf.String())
+}
@@ -0,0 +1,20 @@
+package orderedmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hash returns SHA-256 hash of the JSON encoded content, as a hex string.
+// Keys of the map and nested maps are sorted internally, so maps with the same content have the same hash,
+// regardless of the order of the keys. The receiver is not modified.
+// A value that cannot be encoded to JSON, for example a func, is hashed by its fmt "%#v" representation.
+func (o *OrderedMap) Hash() string {
+	h := sha256.New()
+	e := newJSONEncoder(h, true)
+	e.sortKeys = true
+	e.fmtFallback = true
+	// Writing to the hash never fails and each value can be encoded by the fallback, so there is no error
+	_ = e.encodeValue(o)
+	return hex.EncodeToString(h.Sum(nil))
+}
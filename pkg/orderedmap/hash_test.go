@@ -0,0 +1,61 @@
+package orderedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_Hash(t *testing.T) {
+	t.Parallel()
+	nested1 := New()
+	nested1.Set("x", 1)
+	nested1.Set("y", []any{"a", FromPairs([]Pair{{Key: "k2", Value: true}, {Key: "k1", Value: false}})})
+	map1 := New()
+	map1.Set("b", "value")
+	map1.Set("a", nested1)
+
+	nested2 := New()
+	nested2.Set("y", []any{"a", FromPairs([]Pair{{Key: "k1", Value: false}, {Key: "k2", Value: true}})})
+	nested2.Set("x", 1)
+	map2 := New()
+	map2.Set("a", nested2)
+	map2.Set("b", "value")
+
+	// Different insertion order, the same hash
+	hash1 := map1.Hash()
+	hash2 := map2.Hash()
+	assert.Equal(t, hash1, hash2)
+	assert.Len(t, hash1, 64)
+
+	// Receiver is not modified
+	assert.Equal(t, []string{"b", "a"}, map1.Keys())
+	assert.Equal(t, []string{"x", "y"}, nested1.Keys())
+
+	// Different content, different hash
+	map2.Set("b", "modified")
+	hash3 := map2.Hash()
+	assert.NotEqual(t, hash1, hash3)
+
+	// Different order of slice items, different hash
+	map3 := New()
+	map3.Set("s", []any{1, 2})
+	map4 := New()
+	map4.Set("s", []any{2, 1})
+	hash4 := map3.Hash()
+	hash5 := map4.Hash()
+	assert.NotEqual(t, hash4, hash5)
+
+	// Value that cannot be encoded to JSON is hashed by fmt
+	fn := func() {}
+	map4.Set("fn", fn)
+	map5 := New()
+	map5.Set("s", []any{2, 1})
+	map5.Set("fn", fn)
+	assert.NotPanics(t, func() {
+		assert.Equal(t, map4.Hash(), map5.Hash())
+		assert.NotEqual(t, hash5, map4.Hash())
+	})
+	map5.Set("fn", make(chan int))
+	assert.NotEqual(t, map4.Hash(), map5.Hash())
+}
@@ -3,29 +3,408 @@ package orderedmap
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 )
 
+// SlicePolicy defines encoding of nil and empty slices, see MarshalJSONCanonical.
+type SlicePolicy int
+
+const (
+	// KeepSlices encodes nil slice as null and empty slice as [], the same as json.Marshal.
+	KeepSlices SlicePolicy = iota
+	// NilSlicesAsEmpty encodes both nil and empty slice as [].
+	NilSlicesAsEmpty
+	// EmptySlicesAsNull encodes both nil and empty slice as null.
+	EmptySlicesAsNull
+)
+
+// CanonicalOptions for the MarshalJSONCanonical function.
+type CanonicalOptions struct {
+	Slices SlicePolicy
+}
+
+// PrettyOptions for the MarshalPretty method.
+type PrettyOptions struct {
+	// Prefix and Indent are the same as in json.MarshalIndent, the default Indent is two spaces.
+	Prefix string
+	Indent string
+	// SortKeys sorts keys of all maps alphabetically.
+	SortKeys bool
+	// SortKeysFn sorts keys of the map if it returns true, path is the Path to the map, the root map has empty Path.
+	// If it is set, SortKeys is ignored.
+	SortKeysFn func(path Path) bool
+	// CollapseEmpty writes empty OrderedMaps and slices on one line, as {} and [].
+	// Otherwise, the closing bracket is written on a new line.
+	CollapseEmpty bool
+}
+
+// Decoder reads and decodes OrderedMap values from an input stream.
+// Unlike UnmarshalJSON, the ordered structure is built in a single pass from JSON tokens,
+// so the document is not decoded into map[string]any first.
+type Decoder struct {
+	dec *json.Decoder
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// FromJSON decodes OrderedMap from JSON.
+func FromJSON(b []byte) (*OrderedMap, error) {
+	o := New()
+	if err := json.Unmarshal(b, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ToJSON encodes OrderedMap to JSON.
+func (o *OrderedMap) ToJSON() ([]byte, error) {
+	return json.Marshal(o)
+}
+
+// MustToJSON encodes OrderedMap to JSON, it panics on error.
+func (o *OrderedMap) MustToJSON() []byte {
+	out, err := o.ToJSON()
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// ToJSONIndent encodes OrderedMap to indented JSON, see json.MarshalIndent.
+func (o *OrderedMap) ToJSONIndent(prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(o, prefix, indent)
+}
+
 // MarshalJSON implements JSON encoding.
 func (o OrderedMap) MarshalJSON() ([]byte, error) {
+	return o.marshalJSON(true)
+}
+
+// MarshalJSONNoEscape encodes OrderedMap to JSON, characters <, > and & are not escaped.
+// Nested values, including OrderedMaps inside native maps and slices, are encoded without escaping too.
+// Nil OrderedMap is encoded as null.
+func MarshalJSONNoEscape(o *OrderedMap) ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	return o.marshalJSON(false)
+}
+
+// String returns compact JSON representation of the OrderedMap, for example for logs and tests.
+func (o *OrderedMap) String() string {
+	if o == nil {
+		return "null"
+	}
+	out, err := o.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("!orderedmap(%s)", err)
+	}
+	return string(out)
+}
+
+// MarshalJSONCanonical encodes OrderedMap to JSON, nil and empty slices are encoded according to the options.
+// Nil OrderedMap is encoded as null.
+func MarshalJSONCanonical(o *OrderedMap, opts CanonicalOptions) ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	e := newJSONEncoder(&buf, true)
+	e.slices = opts.Slices
+	if err := e.encodeOrderedMap(*o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteJSON encodes OrderedMap to JSON and writes it to the writer.
+// Keys and values, including nested OrderedMaps and slices, are written one by one, the whole document is not buffered.
+func (o *OrderedMap) WriteJSON(w io.Writer) error {
+	return newJSONEncoder(w, true).encodeValue(o)
+}
+
+// MarshalPretty encodes OrderedMap to indented JSON, keys are sorted and empty containers are collapsed according to the options.
+// The receiver is not modified.
+func (o *OrderedMap) MarshalPretty(opts PrettyOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	e := newJSONEncoder(&buf, true)
+	e.pretty = true
+	e.prefix = opts.Prefix
+	e.indent = opts.Indent
+	if e.indent == "" {
+		e.indent = "  "
+	}
+	e.sortKeys = opts.SortKeys
+	e.sortKeysFn = opts.SortKeysFn
+	e.expandEmpty = !opts.CollapseEmpty
+	if err := e.encodeValue(o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalJSONC encodes OrderedMap to indented JSON with comments, see SetComment.
+// Each line of a comment is written as a "// " line before the key.
+// The output is not a valid JSON, it can be read by JSONC or JSON5 parsers.
+func (o *OrderedMap) MarshalJSONC() ([]byte, error) {
 	var buf bytes.Buffer
-	buf.WriteByte('{')
-	encoder := json.NewEncoder(&buf)
-	for i, k := range o.keys {
+	e := newJSONEncoder(&buf, true)
+	e.pretty = true
+	e.indent = "  "
+	e.comments = true
+	if err := e.encodeValue(o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteJSONIndent encodes OrderedMap to indented JSON and writes it to the writer, see WriteJSON and json.MarshalIndent.
+func (o *OrderedMap) WriteJSONIndent(w io.Writer, prefix, indent string) error {
+	e := newJSONEncoder(w, true)
+	e.pretty = true
+	e.prefix = prefix
+	e.indent = indent
+	return e.encodeValue(o)
+}
+
+func (o OrderedMap) marshalJSON(escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := newJSONEncoder(&buf, escapeHTML).encodeOrderedMap(o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonEncoder encodes OrderedMap, nested OrderedMaps and slices to the writer.
+// Other values are encoded by the json.Encoder using a small scratch buffer.
+type jsonEncoder struct {
+	w       io.Writer
+	scratch *bytes.Buffer
+	encoder *json.Encoder
+	slices  SlicePolicy
+	pretty  bool
+	prefix  string
+	indent  string
+	depth   int
+	// sortKeys is true if keys of OrderedMaps should be sorted alphabetically, the maps are not modified.
+	sortKeys bool
+	// sortKeysFn decides whether keys of an OrderedMap should be sorted, if it is set, path is tracked.
+	sortKeysFn func(path Path) bool
+	path       Path
+	// comments is true if comments of the keys should be written, the output is indented JSONC.
+	comments bool
+	// expandEmpty is true if the closing bracket of an empty OrderedMap or slice should be written on a new line.
+	expandEmpty bool
+	// fmtFallback is true if a value that cannot be encoded to JSON should be encoded as its fmt "%#v" string, see Hash.
+	fmtFallback bool
+}
+
+func newJSONEncoder(w io.Writer, escapeHTML bool) *jsonEncoder {
+	scratch := &bytes.Buffer{}
+	encoder := json.NewEncoder(scratch)
+	encoder.SetEscapeHTML(escapeHTML)
+	return &jsonEncoder{w: w, scratch: scratch, encoder: encoder}
+}
+
+func (e *jsonEncoder) write(s string) error {
+	_, err := io.WriteString(e.w, s)
+	return err
+}
+
+// newLine writes a new line and indentation, if the output is indented.
+func (e *jsonEncoder) newLine() error {
+	if !e.pretty {
+		return nil
+	}
+	return e.write("\n" + e.prefix + strings.Repeat(e.indent, e.depth))
+}
+
+func (e *jsonEncoder) encodeOrderedMap(o OrderedMap) error {
+	keys := o.keys
+	if e.shouldSortKeys() {
+		keys = slices.Clone(keys)
+		sort.Strings(keys)
+	}
+	return e.encodeMap(keys, o.values, o.Comment)
+}
+
+// encodeNativeMap encodes map[string]any, keys are sorted, the same as in json.Marshal.
+// Nested values are encoded by the jsonEncoder, so the options apply to them too.
+func (e *jsonEncoder) encodeNativeMap(m map[string]any) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return e.encodeMap(keys, m, nil)
+}
+
+// encodeMap encodes the values in the order of the keys, comment is optional.
+func (e *jsonEncoder) encodeMap(keys []string, values map[string]any, comment func(key string) string) error {
+	if err := e.write("{"); err != nil {
+		return err
+	}
+	e.depth++
+	for i, k := range keys {
 		if i > 0 {
-			buf.WriteByte(',')
+			if err := e.write(","); err != nil {
+				return err
+			}
+		}
+		if err := e.newLine(); err != nil {
+			return err
+		}
+		// add comment
+		if comment != nil {
+			if err := e.encodeComment(comment(k)); err != nil {
+				return err
+			}
 		}
 		// add key
-		if err := encoder.Encode(k); err != nil {
-			return nil, err
+		if err := e.encodeValue(k); err != nil {
+			return err
+		}
+		separator := ":"
+		if e.pretty {
+			separator = ": "
+		}
+		if err := e.write(separator); err != nil {
+			return err
 		}
-		buf.WriteByte(':')
 		// add value
-		if err := encoder.Encode(o.values[k]); err != nil {
-			return nil, err
+		e.enter(MapStep(k))
+		if err := e.encodeValue(values[k]); err != nil {
+			return err
 		}
+		e.leave()
+	}
+	e.depth--
+	if len(keys) > 0 || e.expandEmpty {
+		if err := e.newLine(); err != nil {
+			return err
+		}
+	}
+	return e.write("}")
+}
+
+// encodeComment writes each line of the comment as a "// " line, if comments are enabled.
+func (e *jsonEncoder) encodeComment(comment string) error {
+	if !e.comments || comment == "" {
+		return nil
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if err := e.write(strings.TrimRight("// "+line, " ")); err != nil {
+			return err
+		}
+		if err := e.newLine(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *jsonEncoder) encodeSlice(s []any) error {
+	if err := e.write("["); err != nil {
+		return err
+	}
+	e.depth++
+	for i, item := range s {
+		if i > 0 {
+			if err := e.write(","); err != nil {
+				return err
+			}
+		}
+		if err := e.newLine(); err != nil {
+			return err
+		}
+		e.enter(SliceStep(i))
+		if err := e.encodeValue(item); err != nil {
+			return err
+		}
+		e.leave()
+	}
+	e.depth--
+	if len(s) > 0 || e.expandEmpty {
+		if err := e.newLine(); err != nil {
+			return err
+		}
+	}
+	return e.write("]")
+}
+
+func (e *jsonEncoder) shouldSortKeys() bool {
+	if e.sortKeysFn != nil {
+		return e.sortKeysFn(slices.Clone(e.path))
+	}
+	return e.sortKeys
+}
+
+// enter adds the step to the path, the path is tracked only if it is needed.
+func (e *jsonEncoder) enter(step Step) {
+	if e.sortKeysFn != nil {
+		e.path = append(e.path, step)
+	}
+}
+
+func (e *jsonEncoder) leave() {
+	if e.sortKeysFn != nil {
+		e.path = e.path[:len(e.path)-1]
 	}
-	buf.WriteByte('}')
-	return buf.Bytes(), nil
+}
+
+func (e *jsonEncoder) encodeValue(value any) error {
+	if e.slices != KeepSlices {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
+			switch {
+			case e.slices == NilSlicesAsEmpty && v.IsNil():
+				return e.write("[]")
+			case e.slices == EmptySlicesAsNull && v.Len() == 0:
+				return e.write("null")
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case *OrderedMap:
+		if v != nil {
+			return e.encodeOrderedMap(*v)
+		}
+	case OrderedMap:
+		return e.encodeOrderedMap(v)
+	case map[string]any:
+		if v != nil {
+			return e.encodeNativeMap(v)
+		}
+	case []any:
+		if v != nil {
+			return e.encodeSlice(v)
+		}
+	}
+
+	e.scratch.Reset()
+	if e.pretty {
+		e.encoder.SetIndent(e.prefix+strings.Repeat(e.indent, e.depth), e.indent)
+	}
+	if err := e.encoder.Encode(value); err != nil {
+		if !e.fmtFallback {
+			return err
+		}
+		e.scratch.Reset()
+		if err := e.encoder.Encode(fmt.Sprintf("%#v", value)); err != nil {
+			return err
+		}
+	}
+	// Remove new line added by the encoder
+	_, err := e.w.Write(e.scratch.Bytes()[:e.scratch.Len()-1])
+	return err
 }
 
 // UnmarshalJSON implements JSON decoding.
@@ -42,7 +421,135 @@ func (o *OrderedMap) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	o.keys = make([]string, 0, len(o.values))
-	return decodeJsonOrderedMap(dec, o)
+	if err := decodeJsonOrderedMap(dec, o); err != nil {
+		return err
+	}
+	o.reindexKeys()
+	return nil
+}
+
+// UnmarshalJSONStrict decodes JSON object into the OrderedMap, like UnmarshalJSON,
+// but it returns an error if an object, at any level, contains a duplicate key.
+func UnmarshalJSONStrict(b []byte, o *OrderedMap) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if err := checkJsonDuplicateKeys(dec, nil); err != nil {
+		return err
+	}
+	return o.UnmarshalJSON(b)
+}
+
+// checkJsonDuplicateKeys reads the next JSON value and returns an error if an object contains a duplicate key.
+func checkJsonDuplicateKeys(dec *json.Decoder, path Path) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		seen := make(map[string]bool)
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			if seen[key] {
+				return fmt.Errorf(`duplicate key "%s" at path "%s"`, key, path)
+			}
+			seen[key] = true
+			if err := checkJsonDuplicateKeys(dec, append(path[:len(path):len(path)], MapStep(key))); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for index := 0; dec.More(); index++ {
+			if err := checkJsonDuplicateKeys(dec, append(path[:len(path):len(path)], SliceStep(index))); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Skip '}' or ']'
+	_, err = dec.Token()
+	return err
+}
+
+// Decode reads the next JSON object from the input and stores it in the OrderedMap.
+// Existing content of the OrderedMap is replaced.
+func (d *Decoder) Decode(o *OrderedMap) error {
+	token, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf(`cannot decode JSON "%v" into orderedmap`, token)
+	}
+	o.keys = []string{}
+	o.values = map[string]any{}
+	o.reindexKeys()
+	return d.decodeMap(o)
+}
+
+func (d *Decoder) decodeMap(o *OrderedMap) error {
+	for d.dec.More() {
+		token, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		value, err := d.decodeValue()
+		if err != nil {
+			return err
+		}
+
+		o.Delete(key) // duplicate key, the last value wins and it is moved to the end
+		o.Set(key, value)
+	}
+
+	// Skip '}'
+	_, err := d.dec.Token()
+	return err
+}
+
+func (d *Decoder) decodeSlice() ([]any, error) {
+	s := make([]any, 0)
+	for d.dec.More() {
+		value, err := d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, value)
+	}
+
+	// Skip ']'
+	_, err := d.dec.Token()
+	return s, err
+}
+
+func (d *Decoder) decodeValue() (any, error) {
+	token, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); ok {
+		switch delim {
+		case '{':
+			m := New()
+			if err := d.decodeMap(m); err != nil {
+				return nil, err
+			}
+			return m, nil
+		case '[':
+			return d.decodeSlice()
+		}
+	}
+	return token, nil
 }
 
 func decodeJsonOrderedMap(dec *json.Decoder, o *OrderedMap) error {
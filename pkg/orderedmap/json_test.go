@@ -1,7 +1,11 @@
 package orderedmap
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -259,6 +263,29 @@ func TestOrderedMap_UnmarshalJSON_DuplicateKeys(t *testing.T) {
 	}), o)
 }
 
+func TestUnmarshalJSONStrict(t *testing.T) {
+	t.Parallel()
+	o := New()
+	assert.NoError(t, UnmarshalJSONStrict([]byte(`{"a": {"b": [{"x": 1}, {"x": 2}]}, "c": 3}`), o))
+	assert.Equal(t, `{"a":{"b":[{"x":1},{"x":2}]},"c":3}`, o.String())
+}
+
+func TestUnmarshalJSONStrict_DuplicateKeys(t *testing.T) {
+	t.Parallel()
+	cases := []struct{ input, err string }{
+		{input: `{"x": 1, "y": 2, "x": 3}`, err: `duplicate key "x" at path ""`},
+		{input: `{"a": {"b": {"x": 1, "x": 2}}}`, err: `duplicate key "x" at path "a.b"`},
+		{input: `{"a": [{"x": 1}, {"x": 1, "x": 2}]}`, err: `duplicate key "x" at path "a[1]"`},
+	}
+	for _, c := range cases {
+		o := New()
+		err := UnmarshalJSONStrict([]byte(c.input), o)
+		if assert.Error(t, err, c.input) {
+			assert.Equal(t, c.err, err.Error(), c.input)
+		}
+	}
+}
+
 func TestOrderedMap_UnmarshalJSON_SpecialChars(t *testing.T) {
 	t.Parallel()
 	in := `{ " \u0041\n\r\t\\\\\\\\\\\\ "  : { "\\\\\\" : "\\\\\"\\" }, "\\":  " \\\\ test ", "\n": "\r" }`
@@ -362,3 +389,329 @@ func TestOrderedMap_UnmarshalJSON_Struct(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, float64(1), value)
 }
+
+func TestDecoder_Decode(t *testing.T) {
+	t.Parallel()
+	fixtures := []string{
+		`{}`,
+		`{"x":[]}`,
+		`{"x":{}}`,
+		`{"number": 4, "string": "x", "bool": true, "null": null, "slice": ["1", 1, {"a": 1}, [{"b": 2}, []]]}`,
+		`{"orderedmap": {"e": 1, "a { nested key with brace": "with a }}}} }} {{{ brace value", "after": {"link": "test"}}}`,
+		`{"a": [{}, []], "b": {"x":[1]}, "c": "x", "d": {"x":1}, "b": [{"x":[]}], "c": 1, "d": {"y": 2}, "e": [{"x":1}], "e": [[]], "e": [{"z":2}], "a": {}, "b": [[1]]}`,
+		`{ " A\n\r\t\\\\\\\\\\\\ "  : { "\\\\\\" : "\\\\\"\\" }, "\\":  " \\\\ test ", "\n": "\r" }`,
+		`{"strings": ["a", "b", "c"], "breakdown": [{"name": "a", "percent": 0.9}, {"name": "b", "percent": 0.4}]}`,
+	}
+
+	for _, fixture := range fixtures {
+		expected := New()
+		assert.NoError(t, json.Unmarshal([]byte(fixture), expected), fixture)
+
+		actual := New()
+		assert.NoError(t, NewDecoder(strings.NewReader(fixture)).Decode(actual), fixture)
+		assert.Equal(t, expected, actual, fixture)
+	}
+}
+
+func TestDecoder_Decode_Stream(t *testing.T) {
+	t.Parallel()
+	dec := NewDecoder(strings.NewReader(`{"b": 1, "a": 2} {"c": 3}`))
+
+	o := New()
+	assert.NoError(t, dec.Decode(o))
+	assert.Equal(t, []string{"b", "a"}, o.Keys())
+	assert.NoError(t, dec.Decode(o))
+	assert.Equal(t, []string{"c"}, o.Keys())
+	assert.Equal(t, io.EOF, dec.Decode(o))
+}
+
+func TestDecoder_Decode_Invalid(t *testing.T) {
+	t.Parallel()
+	err := NewDecoder(strings.NewReader(`[1, 2]`)).Decode(New())
+	assert.Error(t, err)
+	assert.Equal(t, `cannot decode JSON "[" into orderedmap`, err.Error())
+
+	err = NewDecoder(strings.NewReader(`{"a": [1, 2}`)).Decode(New())
+	assert.Error(t, err)
+}
+
+func TestMarshalJSONNoEscape(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("query", "SELECT * FROM t WHERE a < b && c > d")
+	o := New()
+	o.Set("value", "a < b && c > d")
+	o.Set("nested", nested)
+	o.Set("slice", []any{"<tag>", nested})
+
+	out, err := MarshalJSONNoEscape(o)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"value":"a < b && c > d","nested":{"query":"SELECT * FROM t WHERE a < b && c > d"},"slice":["<tag>",{"query":"SELECT * FROM t WHERE a < b && c > d"}]}`, string(out))
+
+	// Round trip
+	decoded := New()
+	assert.NoError(t, json.Unmarshal(out, decoded))
+	assert.Equal(t, "a < b && c > d", decoded.GetOrNil("value"))
+	assert.Equal(t, o, decoded)
+
+	// Default encoding escapes HTML characters
+	out, err = json.Marshal(o)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"value":"a \u003c b \u0026\u0026 c \u003e d","nested":{"query":"SELECT * FROM t WHERE a \u003c b \u0026\u0026 c \u003e d"},"slice":["\u003ctag\u003e",{"query":"SELECT * FROM t WHERE a \u003c b \u0026\u0026 c \u003e d"}]}`, string(out))
+
+	// OrderedMap nested in a native map
+	o = New()
+	o.Set("native", map[string]any{"z": "<z>", "a": []any{nested}, "empty": map[string]any{}})
+	out, err = MarshalJSONNoEscape(o)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"native":{"a":[{"query":"SELECT * FROM t WHERE a < b && c > d"}],"empty":{},"z":"<z>"}}`, string(out))
+
+	// Nil map
+	out, err = MarshalJSONNoEscape(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `null`, string(out))
+}
+
+func TestOrderedMap_String(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("b", []any{1, "2"})
+	o := New()
+	o.Set("z", "value")
+	o.Set("a", nested)
+
+	assert.Equal(t, `{"z":"value","a":{"b":[1,"2"]}}`, o.String())
+	assert.Equal(t, `{"z":"value","a":{"b":[1,"2"]}}`, fmt.Sprintf("%v", o))
+	assert.Equal(t, `{}`, New().String())
+	assert.Equal(t, `null`, (*OrderedMap)(nil).String())
+
+	// Encoding error
+	o.Set("invalid", func() {})
+	assert.Equal(t, `!orderedmap(json: unsupported type: func())`, o.String())
+}
+
+func TestDecoder_Slices(t *testing.T) {
+	t.Parallel()
+	in := `{"strings": ["a", "b"], "mixed": ["a", 1], "empty": [], "nested": {"strings": ["c"]}}`
+
+	// []any is used, the same as in UnmarshalJSON
+	expected := New()
+	assert.NoError(t, json.Unmarshal([]byte(in), expected))
+	assert.Equal(t, []any{"a", "b"}, expected.GetOrNil("strings"))
+
+	o := New()
+	assert.NoError(t, NewDecoder(strings.NewReader(in)).Decode(o))
+	assert.Equal(t, expected, o)
+	assert.Equal(t, []any{"c"}, o.GetNestedOrNil("nested.strings"))
+}
+
+func TestFromJSON(t *testing.T) {
+	t.Parallel()
+	o, err := FromJSON([]byte(`{"z": 1, "a": {"c": 2, "b": 3}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"z", "a"}, o.Keys())
+	assert.Equal(t, []string{"c", "b"}, o.GetOrNil("a").(*OrderedMap).Keys())
+
+	_, err = FromJSON([]byte(`{"z": `))
+	assert.Error(t, err)
+}
+
+func TestOrderedMap_ToJSON(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("c", 2)
+	nested.Set("b", 3)
+	o := New()
+	o.Set("z", 1)
+	o.Set("a", nested)
+
+	out, err := o.ToJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"z":1,"a":{"c":2,"b":3}}`, string(out))
+	assert.Equal(t, `{"z":1,"a":{"c":2,"b":3}}`, string(o.MustToJSON()))
+
+	expected, err := json.MarshalIndent(o, ">", "\t")
+	assert.NoError(t, err)
+	out, err = o.ToJSONIndent(">", "\t")
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(out))
+
+	o.Set("invalid", func() {})
+	assert.Panics(t, func() {
+		o.MustToJSON()
+	})
+}
+
+func TestMarshalJSONCanonical(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("nilStrings", []string(nil))
+	nested.Set("emptyStrings", []string{})
+	o := New()
+	o.Set("nil", []any(nil))
+	o.Set("empty", []any{})
+	o.Set("items", []any{1, []any(nil)})
+	o.Set("nested", nested)
+
+	// Default, the same as MarshalJSON
+	out, err := MarshalJSONCanonical(o, CanonicalOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"nil":null,"empty":[],"items":[1,null],"nested":{"nilStrings":null,"emptyStrings":[]}}`, string(out))
+	assert.Equal(t, o.String(), string(out))
+
+	// Nil slices as empty
+	out, err = MarshalJSONCanonical(o, CanonicalOptions{Slices: NilSlicesAsEmpty})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"nil":[],"empty":[],"items":[1,[]],"nested":{"nilStrings":[],"emptyStrings":[]}}`, string(out))
+
+	// Empty slices as null
+	out, err = MarshalJSONCanonical(o, CanonicalOptions{Slices: EmptySlicesAsNull})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"nil":null,"empty":null,"items":[1,null],"nested":{"nilStrings":null,"emptyStrings":null}}`, string(out))
+
+	// Nil map
+	out, err = MarshalJSONCanonical(nil, CanonicalOptions{Slices: NilSlicesAsEmpty})
+	assert.NoError(t, err)
+	assert.Equal(t, `null`, string(out))
+}
+
+func TestOrderedMap_WriteJSON(t *testing.T) {
+	t.Parallel()
+	o, err := FromJSON([]byte(multiLevelJSON))
+	assert.NoError(t, err)
+	o.Set("html", "<a&b>")
+	o.Set("empty", New())
+	o.Set("emptySlice", []any{})
+	o.Set("nilSlice", []any(nil))
+	o.Set("native", map[string]any{"b": []int{1, 2}, "a": struct{ X int }{X: 1}})
+	o.Set("nested", []any{New(), []any{FromPairs([]Pair{{Key: "z", Value: 1}, {Key: "y", Value: []any{}}})}})
+
+	// Compact
+	var buf bytes.Buffer
+	assert.NoError(t, o.WriteJSON(&buf))
+	expected, err := json.Marshal(o)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), buf.String())
+
+	// Indented
+	buf.Reset()
+	assert.NoError(t, o.WriteJSONIndent(&buf, "//", "\t"))
+	expected, err = json.MarshalIndent(o, "//", "\t")
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), buf.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(_ []byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
+
+func TestOrderedMap_WriteJSON_Error(t *testing.T) {
+	t.Parallel()
+	o := FromPairs([]Pair{{Key: "foo", Value: "bar"}})
+	assert.EqualError(t, o.WriteJSON(failingWriter{}), "write failed")
+	assert.EqualError(t, o.WriteJSONIndent(failingWriter{}, "", "  "), "write failed")
+}
+
+func TestOrderedMap_MarshalPretty(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("z", 1)
+	nested.Set("y", New())
+	o := New()
+	o.Set("b", []any{FromPairs([]Pair{{Key: "d", Value: 1}, {Key: "c", Value: 2}})})
+	o.Set("a", nested)
+	o.Set("c", []any{})
+
+	// Unsorted
+	out, err := o.MarshalPretty(PrettyOptions{CollapseEmpty: true})
+	assert.NoError(t, err)
+	expected := `{
+  "b": [
+    {
+      "d": 1,
+      "c": 2
+    }
+  ],
+  "a": {
+    "z": 1,
+    "y": {}
+  },
+  "c": []
+}`
+	assert.Equal(t, expected, string(out))
+
+	// Sorted
+	out, err = o.MarshalPretty(PrettyOptions{Indent: "\t", SortKeys: true, CollapseEmpty: true})
+	assert.NoError(t, err)
+	expected = "{\n\t\"a\": {\n\t\t\"y\": {},\n\t\t\"z\": 1\n\t},\n\t\"b\": [\n\t\t{\n\t\t\t\"c\": 2,\n\t\t\t\"d\": 1\n\t\t}\n\t],\n\t\"c\": []\n}"
+	assert.Equal(t, expected, string(out))
+
+	// Sorted by the path predicate, only the maps in the "b" slice
+	var paths []string
+	out, err = o.MarshalPretty(PrettyOptions{CollapseEmpty: true, SortKeysFn: func(path Path) bool {
+		paths = append(paths, path.String())
+		return len(path) > 0 && path.First() == MapStep("b")
+	}})
+	assert.NoError(t, err)
+	expected = `{
+  "b": [
+    {
+      "c": 2,
+      "d": 1
+    }
+  ],
+  "a": {
+    "z": 1,
+    "y": {}
+  },
+  "c": []
+}`
+	assert.Equal(t, expected, string(out))
+	assert.Equal(t, []string{"", "b[0]", "a", "a.y"}, paths)
+
+	// Empty containers are not collapsed
+	out, err = o.MarshalPretty(PrettyOptions{})
+	assert.NoError(t, err)
+	expected = `{
+  "b": [
+    {
+      "d": 1,
+      "c": 2
+    }
+  ],
+  "a": {
+    "z": 1,
+    "y": {
+    }
+  },
+  "c": [
+  ]
+}`
+	assert.Equal(t, expected, string(out))
+	out, err = New().MarshalPretty(PrettyOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n}", string(out))
+	out, err = New().MarshalPretty(PrettyOptions{CollapseEmpty: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(out))
+
+	// Receiver is not modified
+	assert.Equal(t, []string{"b", "a", "c"}, o.Keys())
+}
+
+func TestOrderedMap_MarshalJSON_Marshalers(t *testing.T) {
+	t.Parallel()
+	id := textID(2)
+	o := New()
+	o.Set("text", textID(1))
+	o.Set("textPtr", &id)
+	o.Set("json", jsonValue{Value: 1})
+	o.Set("slice", []any{textID(3), jsonValue{Value: 2}})
+	o.Set("map", *FromPairs([]Pair{{Key: "b", Value: textID(4)}, {Key: "a", Value: 1}}))
+
+	out, err := json.Marshal(o)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"text":"id-1","textPtr":"id-2","json":{"value":1,"kind":"json","flag":"true"},"slice":["id-3",{"value":2,"kind":"json","flag":"true"}],"map":{"b":"id-4","a":1}}`, string(out))
+}
@@ -9,7 +9,9 @@ package orderedmap
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
 
 	"github.com/keboola/go-utils/pkg/deepcopy"
 )
@@ -34,11 +36,23 @@ func (a ByPair) Less(i, j int) bool { return a.LessFunc(a.Pairs[i], a.Pairs[j])
 type OrderedMap struct {
 	keys   []string
 	values map[string]any
+	// caseInsensitive map matches keys case-insensitively, see NewCaseInsensitive.
+	caseInsensitive bool
+	// lowerKeys maps lowercase key to the stored key, it is used only by case-insensitive map.
+	lowerKeys map[string]string
+	// comments maps the stored key to its comment, see SetComment.
+	comments map[string]string
 }
 
 // VisitCallback callback to visit each nested value in OrderedMap.
 type VisitCallback func(path Path, value any, parent any)
 
+// Match is a nested value found by GetNestedAll.
+type Match struct {
+	Path  Path
+	Value any
+}
+
 // New creates new OrderedMap.
 func New() *OrderedMap {
 	o := OrderedMap{}
@@ -47,6 +61,16 @@ func New() *OrderedMap {
 	return &o
 }
 
+// NewCaseInsensitive creates new OrderedMap with case-insensitive keys.
+// Set, Get and Delete match keys case-insensitively, the first-seen casing of a key is stored and returned by Keys.
+// UnmarshalJSON stores the raw decoded keys, so keys differing only in casing are not merged.
+func NewCaseInsensitive() *OrderedMap {
+	o := New()
+	o.caseInsensitive = true
+	o.lowerKeys = map[string]string{}
+	return o
+}
+
 // FromPairs creates ordered map from Pairs.
 func FromPairs(pairs []Pair) *OrderedMap {
 	ordered := New()
@@ -56,22 +80,57 @@ func FromPairs(pairs []Pair) *OrderedMap {
 	return ordered
 }
 
+// FromMap creates ordered map from native Go map.
+// Keys are sorted alphabetically, because the source map is unordered.
+// Nested map[string]any values, also inside []any, are converted to *OrderedMap.
+func FromMap(m map[string]any) *OrderedMap {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ordered := New()
+	for _, k := range keys {
+		ordered.Set(k, convertFromMap(m[k]))
+	}
+	return ordered
+}
+
 // Clone clones ordered map using deepcopy.
 func (o *OrderedMap) Clone() *OrderedMap {
 	return deepcopy.Copy(o).(*OrderedMap)
 }
 
+// CloneNested clones nested OrderedMap defined by path, eg. "parameters.foo", using deepcopy.
+func (o *OrderedMap) CloneNested(path string) (*OrderedMap, error) {
+	m, found, err := o.GetNestedMap(path)
+	if !found {
+		return nil, fmt.Errorf(`path "%s" not found`, PathFromStr(path))
+	} else if err != nil {
+		return nil, err
+	}
+	return m.Clone(), nil
+}
+
 // HandleDeepCopy implements deepcopy operation.
-func (o *OrderedMap) HandleDeepCopy(callback deepcopy.TranslateFn, steps deepcopy.Path, visited deepcopy.VisitedPtrMap) (*OrderedMap, deepcopy.CloneFn) {
+func (o *OrderedMap) HandleDeepCopy(callback deepcopy.TranslateFn, steps deepcopy.Path, ctx *deepcopy.Context) (*OrderedMap, deepcopy.CloneFn) {
 	if o == nil {
 		return nil, nil
 	}
-	return New(), func(clone reflect.Value) {
+	clone := New()
+	if o.caseInsensitive {
+		clone = NewCaseInsensitive()
+	}
+	return clone, func(clone reflect.Value) {
 		m := clone.Interface().(*OrderedMap)
 		for _, key := range o.Keys() {
 			value, _ := o.Get(key)
-			keyClone := deepcopy.CopyTranslateSteps(key, callback, steps.Add(MapKeyStep(key)), visited).(string)
-			m.Set(keyClone, deepcopy.CopyTranslateSteps(value, callback, steps.Add(MapStep(key)), visited))
+			keyClone := deepcopy.CopyTranslateContext(key, callback, steps.Add(MapKeyStep(key)), ctx).(string)
+			m.Set(keyClone, deepcopy.CopyTranslateContext(value, callback, steps.Add(MapStep(key)), ctx))
+			if comment := o.Comment(key); comment != "" {
+				m.SetComment(keyClone, comment)
+			}
 		}
 	}
 }
@@ -90,36 +149,100 @@ func (o *OrderedMap) ToMap() map[string]any {
 	return out
 }
 
+// ToMapShallow converts only the top level of the OrderedMap to native Go map.
+// Nested values, for example *OrderedMap or []any, are not converted.
+func (o *OrderedMap) ToMapShallow() map[string]any {
+	if o == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(o.values))
+	for k, v := range o.values {
+		out[k] = v
+	}
+
+	return out
+}
+
 // Get key.
 func (o *OrderedMap) Get(key string) (any, bool) {
-	val, exists := o.values[key]
+	val, exists := o.values[o.storedKey(key)]
 	return val, exists
 }
 
 // GetOrNil gets key or returns nil if it doesn't exists.
 func (o *OrderedMap) GetOrNil(key string) any {
-	return o.values[key]
+	return o.values[o.storedKey(key)]
 }
 
 // Set key.
 func (o *OrderedMap) Set(key string, value any) {
+	key = o.storedKey(key)
 	if _, exists := o.values[key]; !exists {
 		o.keys = append(o.keys, key)
+		if o.caseInsensitive {
+			o.lowerKeys[strings.ToLower(key)] = key
+		}
 	}
 	o.values[key] = value
 }
 
+// SetAll sets the pairs in order, existing keys keep their position, new keys are added to the end.
+func (o *OrderedMap) SetAll(pairs ...Pair) {
+	for _, pair := range pairs {
+		o.Set(pair.Key, pair.Value)
+	}
+}
+
 // SetNested value defined by path, eg. "parameters.foo[123]".
 func (o *OrderedMap) SetNested(path string, value any) error {
 	return o.SetNestedPath(PathFromStr(path), value)
 }
 
 // SetNestedPath value defined by key, eg. Key{MapStep("parameters"), MapStep("foo"), SliceStep(123)}.
+// AppendStep in a slice position appends a new element to the slice, eg. Key{MapStep("list"), AppendStep{}}.
+// If a slice index is beyond the slice length, the gap is filled with nil values.
+// Missing intermediate values are created, []any if the next step is SliceStep or AppendStep, otherwise *OrderedMap.
+// MapStep traverses *OrderedMap and also native map[string]any, the same as GetNestedPath.
+// MapKeyStep as the last step renames the key to the string value, the position and the value of the key are preserved,
+// eg. Key{MapStep("parameters"), MapKeyStep("old")} and value "new".
+// If an error is returned, the map is not modified.
 func (o *OrderedMap) SetNestedPath(path Path, value any) error {
+	return o.setNestedPath(path, value, false)
+}
+
+// SetNestedStrict value defined by path, eg. "parameters.foo[123]".
+// Unlike SetNested, an error is returned if a slice index is beyond the slice length.
+func (o *OrderedMap) SetNestedStrict(path string, value any) error {
+	return o.SetNestedPathStrict(PathFromStr(path), value)
+}
+
+// SetNestedPathStrict value defined by key, eg. Key{MapStep("parameters"), MapStep("foo"), SliceStep(123)}.
+// Unlike SetNestedPath, an error is returned if a slice index is beyond the slice length.
+func (o *OrderedMap) SetNestedPathStrict(path Path, value any) error {
+	return o.setNestedPath(path, value, true)
+}
+
+func (o *OrderedMap) setNestedPath(path Path, value any, strict bool) error {
 	if len(path) == 0 {
 		return fmt.Errorf(`path cannot be empty`)
 	}
 
+	// Rename key
+	if key, ok := path.Last().(MapKeyStep); ok {
+		return o.renameNestedKey(path, key, value)
+	}
+
+	// The whole path is validated first, so a failed set doesn't modify the map
+	if err := o.walkNestedPath(path, value, strict, true); err != nil {
+		return err
+	}
+	return o.walkNestedPath(path, value, strict, false)
+}
+
+// walkNestedPath sets the value, see setNestedPath.
+// If dryRun is true, the map is not modified, missing intermediate values are created only for the walk.
+func (o *OrderedMap) walkNestedPath(path Path, value any, strict, dryRun bool) error {
 	currentKey := make(Path, 0)
 	var current any = o
 
@@ -135,7 +258,8 @@ func (o *OrderedMap) SetNestedPath(path Path, value any) error {
 			nextKey = lastKey
 		}
 
-		if _, ok := nextKey.(SliceStep); ok {
+		switch nextKey.(type) {
+		case SliceStep, AppendStep:
 			return []any{}
 		}
 
@@ -153,7 +277,19 @@ func (o *OrderedMap) SetNestedPath(path Path, value any) error {
 					continue
 				} else {
 					current = newValueFactory(i)
-					m.Set(string(key), current)
+					if !dryRun {
+						m.Set(string(key), current)
+					}
+				}
+			} else if m, ok := current.(map[string]any); ok {
+				if v, found := m[string(key)]; found {
+					current = v
+					continue
+				} else {
+					current = newValueFactory(i)
+					if !dryRun {
+						m[string(key)] = current
+					}
 				}
 			} else {
 				return fmt.Errorf(`path "%s": expected object found "%T"`, currentKey, current)
@@ -168,13 +304,31 @@ func (o *OrderedMap) SetNestedPath(path Path, value any) error {
 				} else if len(s) > int(key) {
 					current = s[key]
 					continue
+				} else if strict && int(key) > len(s) {
+					return fmt.Errorf(`path "%s": array key is out of range, array length is %d`, currentKey, len(s))
 				} else {
-					// Add nil values if the new key isn't immediately after the last
-					s = append(s, make([]any, key.Index()-len(s))...)
-
 					current = newValueFactory(i)
-					err := o.SetNestedPath(currentKey.WithoutLast(), append(s, current))
-					if err != nil {
+					if !dryRun {
+						// Add nil values if the new key isn't immediately after the last
+						s = append(s, make([]any, key.Index()-len(s))...)
+						if err := o.walkNestedPath(currentKey.WithoutLast(), append(s, current), strict, false); err != nil {
+							return err
+						}
+					}
+				}
+			} else {
+				return fmt.Errorf(`path "%s": expected array found "%T"`, currentKey, current)
+			}
+		case AppendStep:
+			if i == 0 {
+				return fmt.Errorf(`first key must be MapStep, found "%T"`, key)
+			}
+			if s, ok := current.([]any); ok {
+				// Replace the AppendStep by the index, so the parent path can be set again without another append
+				currentKey[len(currentKey)-1] = SliceStep(len(s))
+				current = newValueFactory(i)
+				if !dryRun {
+					if err := o.walkNestedPath(currentKey.WithoutLast(), append(s, current), strict, false); err != nil {
 						return err
 					}
 				}
@@ -191,7 +345,14 @@ func (o *OrderedMap) SetNestedPath(path Path, value any) error {
 	// Set value to map
 	if key, ok := lastKey.(MapStep); ok {
 		if m, ok := current.(*OrderedMap); ok {
-			m.Set(string(key), value)
+			if !dryRun {
+				m.Set(string(key), value)
+			}
+			return nil
+		} else if m, ok := current.(map[string]any); ok {
+			if !dryRun {
+				m[string(key)] = value
+			}
 			return nil
 		}
 		return fmt.Errorf(`path "%s": expected object found "%T"`, currentKey, current)
@@ -203,21 +364,155 @@ func (o *OrderedMap) SetNestedPath(path Path, value any) error {
 			if int(key) < 0 {
 				return fmt.Errorf(`path "%s": array key can't be negative`, currentKey)
 			} else if int(key) < len(s) {
-				s[key] = value
+				if !dryRun {
+					s[key] = value
+				}
+				return nil
+			} else if strict && int(key) > len(s) {
+				return fmt.Errorf(`path "%s": array key is out of range, array length is %d`, currentKey, len(s))
+			} else if dryRun {
 				return nil
 			} else {
 				// Add nil values if the new key isn't immediately after the last
 				s = append(s, make([]any, key.Index()-len(s))...)
 
-				return o.SetNestedPath(currentKey.WithoutLast(), append(s, value))
+				return o.walkNestedPath(currentKey.WithoutLast(), append(s, value), strict, false)
 			}
 		}
 		return fmt.Errorf(`path "%s": expected array found "%T"`, currentKey, current)
 	}
 
+	// Append value to slice
+	if _, ok := lastKey.(AppendStep); ok {
+		if s, ok := current.([]any); ok {
+			if dryRun {
+				return nil
+			}
+			return o.walkNestedPath(currentKey.WithoutLast(), append(s, value), strict, false)
+		}
+		return fmt.Errorf(`path "%s": expected array found "%T"`, currentKey, current)
+	}
+
 	return fmt.Errorf(`path "%s": last key must be MapStep of SliceStep, found "%T"`, path, lastKey)
 }
 
+// renameNestedKey renames the key in the nested map, see SetNestedPath.
+func (o *OrderedMap) renameNestedKey(path Path, oldKey MapKeyStep, value any) error {
+	newKey, ok := value.(string)
+	if !ok {
+		return fmt.Errorf(`path "%s": new key must be string, found "%T"`, path, value)
+	}
+
+	// Get parent map, it is not created if it doesn't exist
+	var parent any = o
+	if parentPath := path.WithoutLast(); len(parentPath) > 0 {
+		v, _, err := o.GetNestedPath(parentPath)
+		if err != nil {
+			return err
+		}
+		parent = v
+	}
+	// Native map, the order of the keys is not defined
+	if m, ok := parent.(map[string]any); ok {
+		if _, found := m[oldKey.Key()]; !found {
+			return fmt.Errorf(`path "%s" not found`, path)
+		}
+		if newKey != oldKey.Key() {
+			if _, found := m[newKey]; found {
+				return fmt.Errorf(`path "%s": key "%s" already exists`, path, newKey)
+			}
+			m[newKey] = m[oldKey.Key()]
+			delete(m, oldKey.Key())
+		}
+		return nil
+	}
+
+	m, ok := parent.(*OrderedMap)
+	if !ok {
+		return fmt.Errorf(`path "%s": expected object found "%T"`, path.WithoutLast(), parent)
+	}
+
+	oldStored := m.storedKey(oldKey.Key())
+	if _, found := m.values[oldStored]; !found {
+		return fmt.Errorf(`path "%s" not found`, path)
+	}
+	if newStored := m.storedKey(newKey); newStored != oldStored {
+		if _, found := m.values[newStored]; found {
+			return fmt.Errorf(`path "%s": key "%s" already exists`, path, newKey)
+		}
+	}
+
+	m.renameKey(oldStored, newKey)
+	return nil
+}
+
+// AppendNested appends values to the nested slice defined by path, eg. "parameters.foo".
+// The slice is created if it doesn't exist.
+func (o *OrderedMap) AppendNested(path string, values ...any) error {
+	return o.AppendNestedPath(PathFromStr(path), values...)
+}
+
+// AppendNestedPath appends values to the nested slice defined by key, eg. Key{MapStep("parameters"), MapStep("foo")}.
+// The slice is created if it doesn't exist.
+func (o *OrderedMap) AppendNestedPath(path Path, values ...any) error {
+	value, found, err := o.GetNestedPath(path)
+	if found && err != nil {
+		return err
+	}
+
+	s := make([]any, 0, len(values))
+	if found {
+		if v, ok := value.([]any); ok {
+			s = v
+		} else {
+			return fmt.Errorf(`path "%s": expected array found "%T"`, path, value)
+		}
+	}
+
+	return o.SetNestedPath(path, append(s, values...))
+}
+
+// DeleteNested deletes nested value defined by path, eg. "parameters.foo[123]".
+// Deleted slice item is removed from the slice, the following items are shifted.
+func (o *OrderedMap) DeleteNested(path string) error {
+	return o.DeleteNestedPath(PathFromStr(path))
+}
+
+// DeleteNestedPath deletes nested value defined by key, eg. Key{MapStep("parameters"), MapStep("foo"), SliceStep(123)}.
+// Deleted slice item is removed from the slice, the following items are shifted.
+func (o *OrderedMap) DeleteNestedPath(path Path) error {
+	if _, _, err := o.GetNestedPath(path); err != nil {
+		return err
+	}
+
+	// Delete from the root map
+	parentPath := path.WithoutLast()
+	if len(parentPath) == 0 {
+		if key, ok := path.Last().(MapStep); ok {
+			o.Delete(key.Key())
+			return nil
+		}
+		return fmt.Errorf(`first key must be MapStep, found "%T"`, path.Last())
+	}
+
+	// Delete from the parent map or slice
+	parent, _, _ := o.GetNestedPath(parentPath)
+	switch key := path.Last().(type) {
+	case MapStep:
+		if m, ok := parent.(map[string]any); ok {
+			delete(m, key.Key())
+		} else {
+			parent.(*OrderedMap).Delete(key.Key())
+		}
+		return nil
+	case SliceStep:
+		s := parent.([]any)
+		return o.SetNestedPath(parentPath, append(s[:key.Index():key.Index()], s[key.Index()+1:]...))
+	default:
+		return fmt.Errorf(`unexpected type "%T"`, key)
+	}
+}
+
 // GetNestedOrNil returns nil if values is not found or an error occurred.
 func (o *OrderedMap) GetNestedOrNil(path string) any {
 	return o.GetNestedPathOrNil(PathFromStr(path))
@@ -234,6 +529,33 @@ func (o *OrderedMap) GetNestedPathOrNil(path Path) any {
 	return value
 }
 
+// GetNestedMulti returns nested values by paths, eg. "parameters.foo[123]".
+// The result maps each path to its value, an error is returned for the first path that cannot be resolved.
+func (o *OrderedMap) GetNestedMulti(paths ...string) (map[string]any, error) {
+	out := make(map[string]any, len(paths))
+	for _, path := range paths {
+		value, _, err := o.GetNested(path)
+		if err != nil {
+			return nil, err
+		}
+		out[path] = value
+	}
+	return out, nil
+}
+
+// GetNestedMultiOrNil returns nested values by paths, a value is nil if it is not found or the path cannot be resolved.
+func (o *OrderedMap) GetNestedMultiOrNil(paths ...string) map[string]any {
+	out := make(map[string]any, len(paths))
+	for _, path := range paths {
+		if value, found, err := o.GetNested(path); found && err == nil {
+			out[path] = value
+		} else {
+			out[path] = nil
+		}
+	}
+	return out
+}
+
 // GetNestedMap returns nested OrderedMap by path as string.
 func (o *OrderedMap) GetNestedMap(path string) (m *OrderedMap, found bool, err error) {
 	return o.GetNestedPathMap(PathFromStr(path))
@@ -259,6 +581,7 @@ func (o *OrderedMap) GetNested(path string) (value any, found bool, err error) {
 }
 
 // GetNestedPath returns nested value by Path.
+// MapStep traverses *OrderedMap and also native map[string]any, for example a value constructed by the user.
 func (o *OrderedMap) GetNestedPath(path Path) (value any, found bool, err error) {
 	if len(path) == 0 {
 		return nil, false, fmt.Errorf(`path cannot be empty`)
@@ -278,12 +601,19 @@ func (o *OrderedMap) GetNestedPath(path Path) (value any, found bool, err error)
 				} else {
 					return nil, false, fmt.Errorf(`path "%s" not found`, currentKey)
 				}
+			} else if m, ok := current.(map[string]any); ok {
+				if v, found := m[string(key)]; found {
+					current = v
+					continue
+				} else {
+					return nil, false, fmt.Errorf(`path "%s" not found`, currentKey)
+				}
 			} else {
 				return nil, true, fmt.Errorf(`path "%s": expected object found "%T"`, currentKey.WithoutLast(), current)
 			}
 		case SliceStep:
 			if s, ok := current.([]any); ok {
-				if len(s) > int(key) {
+				if int(key) >= 0 && int(key) < len(s) {
 					current = s[key]
 					continue
 				} else {
@@ -299,13 +629,79 @@ func (o *OrderedMap) GetNestedPath(path Path) (value any, found bool, err error)
 	return current, true, nil
 }
 
+// GetNestedParent returns the container that holds the nested value defined by Path, and the last step to reach the value.
+// The parent is *OrderedMap or map[string]any for MapStep, or []any for SliceStep, so the caller can modify the value in the parent directly.
+// Errors are the same as from GetNestedPath.
+func (o *OrderedMap) GetNestedParent(path Path) (parent any, lastStep Step, found bool, err error) {
+	if _, found, err := o.GetNestedPath(path); err != nil {
+		return nil, nil, found, err
+	}
+
+	parent = o
+	if parentPath := path.WithoutLast(); len(parentPath) > 0 {
+		parent, _, _ = o.GetNestedPath(parentPath)
+	}
+	return parent, path.Last(), true, nil
+}
+
+// GetNestedAll returns all nested values matching the pattern, eg. "parameters.tables[*].id".
+// The "*" as a map step matches all keys of the map, "[*]" matches all indexes of the slice.
+// Branches in which a key or an index is missing are skipped.
+func (o *OrderedMap) GetNestedAll(pattern string) ([]Match, error) {
+	path := patternFromStr(pattern)
+	if len(path) == 0 {
+		return nil, fmt.Errorf(`path cannot be empty`)
+	}
+
+	out := make([]Match, 0)
+	if err := getNestedAll(o, Path{}, path, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // VisitAllRecursive calls callback for each nested key in OrderedMap or []any.
 func (o *OrderedMap) VisitAllRecursive(callback VisitCallback) {
 	visit(Path{}, o, nil, callback)
 }
 
+// TransformValues calls fn for each nested value in OrderedMap or []any.
+// If fn returns true, the value is replaced by the returned value in the parent map or slice.
+// Nested values of the resulting value are visited next.
+func (o *OrderedMap) TransformValues(fn func(path Path, value any) (any, bool)) {
+	transformValues(Path{}, o, fn)
+}
+
+// CountLeaves returns number of all nested values that are not OrderedMap or []any, including slice items.
+func (o *OrderedMap) CountLeaves() int {
+	count := 0
+	o.VisitAllRecursive(func(_ Path, value any, _ any) {
+		switch value.(type) {
+		case *OrderedMap, []any:
+		default:
+			count++
+		}
+	})
+	return count
+}
+
+// MaxDepth returns the maximum nesting level of OrderedMaps and []any, the root map is level 1.
+func (o *OrderedMap) MaxDepth() int {
+	depth := 1
+	o.VisitAllRecursive(func(path Path, value any, _ any) {
+		switch value.(type) {
+		case *OrderedMap, []any:
+			if len(path)+1 > depth {
+				depth = len(path) + 1
+			}
+		}
+	})
+	return depth
+}
+
 // Delete key from map.
 func (o *OrderedMap) Delete(key string) {
+	key = o.storedKey(key)
 	// check key is in use
 	if _, ok := o.values[key]; !ok {
 		return
@@ -319,6 +715,34 @@ func (o *OrderedMap) Delete(key string) {
 	}
 	// remove from values
 	delete(o.values, key)
+	delete(o.comments, key)
+	// remove from case-insensitive index
+	if o.caseInsensitive && o.lowerKeys[strings.ToLower(key)] == key {
+		delete(o.lowerKeys, strings.ToLower(key))
+	}
+}
+
+// renameKey renames the existing stored key, the position and the value are preserved.
+func (o *OrderedMap) renameKey(oldKey, newKey string) {
+	for i, k := range o.keys {
+		if k == oldKey {
+			o.keys[i] = newKey
+			break
+		}
+	}
+	value := o.values[oldKey]
+	delete(o.values, oldKey)
+	o.values[newKey] = value
+	if comment, found := o.comments[oldKey]; found {
+		delete(o.comments, oldKey)
+		o.comments[newKey] = comment
+	}
+	if o.caseInsensitive {
+		if o.lowerKeys[strings.ToLower(oldKey)] == oldKey {
+			delete(o.lowerKeys, strings.ToLower(oldKey))
+		}
+		o.lowerKeys[strings.ToLower(newKey)] = newKey
+	}
 }
 
 // Len returns number of keys.
@@ -326,11 +750,36 @@ func (o *OrderedMap) Len() int {
 	return len(o.keys)
 }
 
+// First returns the first key/value pair, found is false if the map is empty.
+func (o *OrderedMap) First() (pair Pair, found bool) {
+	if len(o.keys) == 0 {
+		return Pair{}, false
+	}
+	key := o.keys[0]
+	return Pair{Key: key, Value: o.values[key]}, true
+}
+
+// Last returns the last key/value pair, found is false if the map is empty.
+func (o *OrderedMap) Last() (pair Pair, found bool) {
+	if len(o.keys) == 0 {
+		return Pair{}, false
+	}
+	key := o.keys[len(o.keys)-1]
+	return Pair{Key: key, Value: o.values[key]}, true
+}
+
 // Keys method returns all keys as slice.
+// The slice is the internal slice of the map, it must not be modified,
+// for example, sorting the slice reorders the map. Use KeysCopy to get a slice that can be modified.
 func (o *OrderedMap) Keys() []string {
 	return o.keys
 }
 
+// KeysCopy method returns a copy of all keys as slice, modification of the slice does not affect the map.
+func (o *OrderedMap) KeysCopy() []string {
+	return slices.Clone(o.keys)
+}
+
 // SortKeys sorts keys using sort func.
 func (o *OrderedMap) SortKeys(sortFunc func(keys []string)) {
 	sortFunc(o.keys)
@@ -350,6 +799,48 @@ func (o *OrderedMap) Sort(lessFunc func(a *Pair, b *Pair) bool) {
 	}
 }
 
+// SortStable sorts keys/values using sort func, equal elements keep their original order.
+func (o *OrderedMap) SortStable(lessFunc func(a *Pair, b *Pair) bool) {
+	pairs := make([]*Pair, len(o.keys))
+	for i, key := range o.keys {
+		pairs[i] = &Pair{key, o.values[key]}
+	}
+
+	sort.Stable(ByPair{pairs, lessFunc})
+
+	for i, pair := range pairs {
+		o.keys[i] = pair.Key
+	}
+}
+
+// storedKey returns the key under which the value is stored.
+// For case-insensitive map, it is the first-seen casing of the key.
+func (o *OrderedMap) storedKey(key string) string {
+	if !o.caseInsensitive {
+		return key
+	}
+	if _, found := o.values[key]; found {
+		return key
+	}
+	if stored, found := o.lowerKeys[strings.ToLower(key)]; found {
+		return stored
+	}
+	return key
+}
+
+// reindexKeys rebuilds case-insensitive index, it is used after keys are modified directly, for example by JSON decoding.
+func (o *OrderedMap) reindexKeys() {
+	if !o.caseInsensitive {
+		return
+	}
+	o.lowerKeys = make(map[string]string, len(o.keys))
+	for _, key := range o.keys {
+		if _, found := o.lowerKeys[strings.ToLower(key)]; !found {
+			o.lowerKeys[strings.ToLower(key)] = key
+		}
+	}
+}
+
 func visit(key Path, valueRaw any, parent any, callback VisitCallback) {
 	// Call callback for not-root item
 	if len(key) != 0 {
@@ -374,6 +865,113 @@ func visit(key Path, valueRaw any, parent any, callback VisitCallback) {
 	}
 }
 
+func transformValues(key Path, value any, fn func(path Path, value any) (any, bool)) {
+	switch v := value.(type) {
+	case *OrderedMap:
+		for _, k := range v.Keys() {
+			subKey := append(make(Path, 0), key...)
+			subKey = append(subKey, MapStep(k))
+			subValue, _ := v.Get(k)
+			if newValue, ok := fn(subKey, subValue); ok {
+				v.Set(k, newValue)
+				subValue = newValue
+			}
+			transformValues(subKey, subValue, fn)
+		}
+	case []any:
+		for index, subValue := range v {
+			subKey := append(make(Path, 0), key...)
+			subKey = append(subKey, SliceStep(index))
+			if newValue, ok := fn(subKey, subValue); ok {
+				v[index] = newValue
+				subValue = newValue
+			}
+			transformValues(subKey, subValue, fn)
+		}
+	}
+}
+
+func getNestedAll(current any, currentKey Path, pattern Path, out *[]Match) error {
+	if len(pattern) == 0 {
+		*out = append(*out, Match{Path: currentKey, Value: current})
+		return nil
+	}
+
+	step := pattern.First()
+	subKey := func(s Step) Path {
+		return append(append(make(Path, 0, len(currentKey)+1), currentKey...), s)
+	}
+
+	switch step := step.(type) {
+	case MapStep, anyKeyStep:
+		var keys []string
+		var get func(key string) (any, bool)
+		switch m := current.(type) {
+		case *OrderedMap:
+			keys, get = m.Keys(), m.Get
+		case map[string]any:
+			// Keys of a native map are traversed in the alphabetical order, the same as in json.Marshal
+			keys = make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			get = func(key string) (any, bool) {
+				v, found := m[key]
+				return v, found
+			}
+		default:
+			return fmt.Errorf(`path "%s": expected object found "%T"`, currentKey, current)
+		}
+		if key, ok := step.(MapStep); ok {
+			if v, found := get(string(key)); found {
+				return getNestedAll(v, subKey(key), pattern.WithoutFirst(), out)
+			}
+			return nil
+		}
+		for _, k := range keys {
+			v, _ := get(k)
+			if err := getNestedAll(v, subKey(MapStep(k)), pattern.WithoutFirst(), out); err != nil {
+				return err
+			}
+		}
+	case SliceStep, anyIndexStep:
+		s, ok := current.([]any)
+		if !ok {
+			return fmt.Errorf(`path "%s": expected array found "%T"`, currentKey, current)
+		}
+		if index, ok := step.(SliceStep); ok {
+			if index.Index() < len(s) {
+				return getNestedAll(s[index], subKey(index), pattern.WithoutFirst(), out)
+			}
+			return nil
+		}
+		for i, v := range s {
+			if err := getNestedAll(v, subKey(SliceStep(i)), pattern.WithoutFirst(), out); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf(`unexpected type "%T"`, step)
+	}
+	return nil
+}
+
+func convertFromMap(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return FromMap(v)
+	case []any:
+		converted := make([]any, 0, len(v))
+		for _, item := range v {
+			converted = append(converted, convertFromMap(item))
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
 func convertToMap(value any) any {
 	switch v := value.(type) {
 	case *OrderedMap:
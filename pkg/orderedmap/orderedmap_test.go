@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/keboola/go-utils/pkg/deepcopy"
 )
 
 func TestOrderedMap(t *testing.T) {
@@ -85,6 +87,116 @@ func TestOrderedMap(t *testing.T) {
 	}
 }
 
+func TestOrderedMap_KeysCopy(t *testing.T) {
+	t.Parallel()
+	o := New()
+	o.Set("b", 1)
+	o.Set("a", 2)
+	o.Set("c", 3)
+
+	// Modification of the copy does not affect the map
+	keys := o.KeysCopy()
+	assert.Equal(t, []string{"b", "a", "c"}, keys)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	assert.Equal(t, []string{"b", "a", "c"}, o.Keys())
+
+	// Modification of the Keys result reorders the map
+	sort.Strings(o.Keys())
+	assert.Equal(t, []string{"a", "b", "c"}, o.KeysCopy())
+
+	// Empty map
+	assert.Equal(t, []string{}, New().KeysCopy())
+}
+
+func TestOrderedMap_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+	o := NewCaseInsensitive()
+	o.Set("foo", 1)
+	o.Set("Bar", 2)
+
+	// Get
+	v, found := o.Get("Foo")
+	assert.True(t, found)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, o.GetOrNil("BAR"))
+
+	// Set keeps the first-seen casing
+	o.Set("FOO", 3)
+	assert.Equal(t, []string{"foo", "Bar"}, o.Keys())
+	assert.Equal(t, 3, o.GetOrNil("foo"))
+
+	// Delete
+	o.Delete("bar")
+	assert.Equal(t, []string{"foo"}, o.Keys())
+	_, found = o.Get("Bar")
+	assert.False(t, found)
+
+	// Clone keeps the case-insensitivity
+	clone := o.Clone()
+	assert.Equal(t, 3, clone.GetOrNil("Foo"))
+
+	// JSON decoding stores the raw keys
+	assert.NoError(t, json.Unmarshal([]byte(`{"Key": 1, "KEY": 2}`), o))
+	assert.Equal(t, []string{"Key", "KEY"}, o.Keys())
+	assert.Equal(t, 1, int(o.GetOrNil("key").(float64)))
+	assert.Equal(t, 2, int(o.GetOrNil("KEY").(float64)))
+
+	// Default map is case-sensitive
+	o = New()
+	o.Set("foo", 1)
+	_, found = o.Get("Foo")
+	assert.False(t, found)
+}
+
+func TestOrderedMap_FirstLast(t *testing.T) {
+	t.Parallel()
+	o := New()
+
+	// Empty map
+	pair, found := o.First()
+	assert.False(t, found)
+	assert.Equal(t, Pair{}, pair)
+	pair, found = o.Last()
+	assert.False(t, found)
+	assert.Equal(t, Pair{}, pair)
+
+	// Single element
+	o.Set("a", 1)
+	pair, found = o.First()
+	assert.True(t, found)
+	assert.Equal(t, Pair{Key: "a", Value: 1}, pair)
+	pair, found = o.Last()
+	assert.True(t, found)
+	assert.Equal(t, Pair{Key: "a", Value: 1}, pair)
+
+	// Multiple elements
+	o.Set("b", 2)
+	o.Set("c", 3)
+	pair, found = o.First()
+	assert.True(t, found)
+	assert.Equal(t, Pair{Key: "a", Value: 1}, pair)
+	pair, found = o.Last()
+	assert.True(t, found)
+	assert.Equal(t, Pair{Key: "c", Value: 3}, pair)
+}
+
+func TestOrderedMap_SetAll(t *testing.T) {
+	t.Parallel()
+	o := New()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.SetAll(Pair{Key: "c", Value: 3}, Pair{Key: "a", Value: 10}, Pair{Key: "d", Value: 4})
+
+	// Existing keys keep their position
+	assert.Equal(t, []string{"a", "b", "c", "d"}, o.Keys())
+	assert.Equal(t, map[string]any{"a": 10, "b": 2, "c": 3, "d": 4}, o.ToMap())
+
+	// No pairs
+	o.SetAll()
+	assert.Equal(t, 4, o.Len())
+}
+
 func TestOrderedMap_SortKeys(t *testing.T) {
 	t.Parallel()
 	s := `
@@ -142,6 +254,29 @@ func TestOrderedMap_Sort(t *testing.T) {
 	}
 }
 
+func TestOrderedMap_SortStable(t *testing.T) {
+	t.Parallel()
+	s := `
+{
+  "e": 2,
+  "d": 1,
+  "c": 2,
+  "b": 1,
+  "a": 2,
+  "f": 3,
+  "g": 1
+}
+`
+	o := New()
+	assert.NoError(t, json.Unmarshal([]byte(s), &o))
+	o.SortStable(func(a *Pair, b *Pair) bool {
+		return a.Value.(float64) < b.Value.(float64)
+	})
+
+	// Pairs with the same value keep their original order
+	assert.Equal(t, []string{"d", "b", "g", "e", "c", "a", "f"}, o.Keys())
+}
+
 // https://github.com/iancoleman/orderedmap/issues/11
 func TestOrderedMap_empty_array(t *testing.T) {
 	t.Parallel()
@@ -193,6 +328,118 @@ func TestOrderedMap_Clone(t *testing.T) {
 	assert.Equal(t, nested, nestedClone)
 }
 
+func TestOrderedMap_CloneNested(t *testing.T) {
+	t.Parallel()
+	root := New()
+	subNested := New()
+	subNested.Set(`key`, `value`)
+	nested := New()
+	nested.Set(`sub`, subNested)
+	nested.Set(`slice`, []any{1, 2})
+	root.Set(`nested`, nested)
+	root.Set(`str`, `value`)
+
+	clone, err := root.CloneNested(`nested`)
+	assert.NoError(t, err)
+	assert.Equal(t, nested, clone)
+	assert.NotSame(t, nested, clone)
+	deepcopy.DeepEqualNotSame(t, nested, clone, "")
+
+	subClone, _ := clone.Get(`sub`)
+	assert.NotSame(t, subNested, subClone)
+
+	// Invalid: path not found
+	_, err = root.CloneNested(`missing.key`)
+	assert.Error(t, err)
+	assert.Equal(t, `path "missing.key" not found`, err.Error())
+
+	// Invalid: path is not a map
+	_, err = root.CloneNested(`str`)
+	assert.Error(t, err)
+	assert.Equal(t, `path "str": expected object, found "string"`, err.Error())
+}
+
+func TestOrderedMap_GetNestedMulti(t *testing.T) {
+	t.Parallel()
+	root := New()
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":"123","parameters":{"tables":[{"name":"a"},{"name":"b"}],"limit":10}}`), root))
+
+	values, err := root.GetNestedMulti(`id`, `parameters.tables[1].name`, `parameters.limit`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		`id`:                        `123`,
+		`parameters.tables[1].name`: `b`,
+		`parameters.limit`:          float64(10),
+	}, values)
+
+	// Not found
+	values, err = root.GetNestedMulti(`id`, `parameters.tables[2].name`, `missing`)
+	assert.Nil(t, values)
+	assert.Error(t, err)
+	assert.Equal(t, `path "parameters.tables[2]" not found`, err.Error())
+
+	// Lenient variant
+	assert.Equal(t, map[string]any{
+		`id`:                        `123`,
+		`parameters.tables[0].name`: `a`,
+		`missing`:                   nil,
+	}, root.GetNestedMultiOrNil(`id`, `parameters.tables[0].name`, `missing`))
+
+	// Lenient variant doesn't panic on a non-map intermediate value
+	assert.NotPanics(t, func() {
+		assert.Equal(t, map[string]any{
+			`id`:         `123`,
+			`id.foo`:     nil,
+			`id[0].name`: nil,
+		}, root.GetNestedMultiOrNil(`id`, `id.foo`, `id[0].name`))
+	})
+}
+
+func TestOrderedMap_GetNestedParent(t *testing.T) {
+	t.Parallel()
+	root := New()
+	nested := New()
+	nested.Set(`key`, `value`)
+	nested.Set(`slice`, []any{1, 2, 3})
+	root.Set(`nested`, nested)
+
+	// Map parent
+	parent, lastStep, found, err := root.GetNestedParent(Path{MapStep(`nested`), MapStep(`key`)})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Same(t, nested, parent)
+	assert.Equal(t, MapStep(`key`), lastStep)
+	parent.(*OrderedMap).Set(string(lastStep.(MapStep)), `modified`)
+	assert.Equal(t, `modified`, root.GetNestedOrNil(`nested.key`))
+
+	// Slice parent
+	parent, lastStep, found, err = root.GetNestedParent(Path{MapStep(`nested`), MapStep(`slice`), SliceStep(1)})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []any{1, 2, 3}, parent)
+	assert.Equal(t, SliceStep(1), lastStep)
+	parent.([]any)[lastStep.(SliceStep)] = 20
+	assert.Equal(t, []any{1, 20, 3}, root.GetNestedOrNil(`nested.slice`))
+
+	// Root parent
+	parent, lastStep, found, err = root.GetNestedParent(Path{MapStep(`nested`)})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Same(t, root, parent)
+	assert.Equal(t, MapStep(`nested`), lastStep)
+
+	// Not found
+	parent, lastStep, found, err = root.GetNestedParent(Path{MapStep(`nested`), SliceStep(3)})
+	assert.Nil(t, parent)
+	assert.Nil(t, lastStep)
+	assert.True(t, found)
+	assert.Equal(t, `path "nested": expected array found "*orderedmap.OrderedMap"`, err.Error())
+	parent, _, found, err = root.GetNestedParent(Path{MapStep(`nested`), MapStep(`missing`)})
+	assert.Nil(t, parent)
+	assert.False(t, found)
+	assert.Equal(t, `path "nested.missing" not found`, err.Error())
+}
+
 func TestOrderedMap_ToMap(t *testing.T) {
 	t.Parallel()
 	root := New()
@@ -207,6 +454,57 @@ func TestOrderedMap_ToMap(t *testing.T) {
 	}, root.ToMap())
 }
 
+func TestFromMap(t *testing.T) {
+	t.Parallel()
+	o := FromMap(map[string]any{
+		`c`: `value`,
+		`a`: map[string]any{
+			`z`: 1,
+			`y`: 2,
+		},
+		`b`: []any{
+			map[string]any{`k2`: true, `k1`: false},
+			`str`,
+			[]any{map[string]any{`x`: nil}},
+		},
+	})
+
+	// Keys are sorted on each level, nested maps are converted
+	out, err := o.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":{"y":2,"z":1},"b":[{"k1":false,"k2":true},"str",[{"x":null}]],"c":"value"}`, string(out))
+	nested, found, err := o.GetNestedMap(`b[0]`)
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`k1`, `k2`}, nested.Keys())
+
+	// Round trip
+	assert.Equal(t, map[string]any{`c`: `value`, `a`: map[string]any{`z`: 1, `y`: 2}}, FromMap(map[string]any{`c`: `value`, `a`: map[string]any{`z`: 1, `y`: 2}}).ToMap())
+
+	// Empty map
+	assert.Empty(t, FromMap(nil).Keys())
+}
+
+func TestOrderedMap_ToMapShallow(t *testing.T) {
+	t.Parallel()
+	root := New()
+	nested := New()
+	nested.Set(`key`, `value`)
+	slice := []any{New()}
+	root.Set(`nested`, nested)
+	root.Set(`slice`, slice)
+	root.Set(`str`, `value`)
+
+	out := root.ToMapShallow()
+	assert.Len(t, out, 3)
+	assert.Same(t, nested, out[`nested`])
+	assert.Same(t, &slice[0], &out[`slice`].([]any)[0])
+	assert.Equal(t, `value`, out[`str`])
+
+	// Nil map
+	assert.Nil(t, (*OrderedMap)(nil).ToMapShallow())
+}
+
 func TestOrderedMapGetNested(t *testing.T) {
 	t.Parallel()
 	root := New()
@@ -280,6 +578,14 @@ func TestOrderedMapGetNested(t *testing.T) {
 	value = root.GetNestedPathOrNil(Path{MapStep(`nested`), MapStep(`slice`), SliceStep(3)})
 	assert.Nil(t, value)
 
+	// Negative slice key
+	assert.NotPanics(t, func() {
+		value, found, err = root.GetNestedPath(Path{MapStep(`nested`), MapStep(`slice`), SliceStep(-1)})
+	})
+	assert.Nil(t, value)
+	assert.False(t, found)
+	assert.Equal(t, `path "nested.slice[-1]" not found`, err.Error())
+
 	// Get nested map - not found
 	value, found, err = root.GetNestedMap(`nested.foo`)
 	assert.Nil(t, value)
@@ -313,6 +619,141 @@ func TestOrderedMapGetNested(t *testing.T) {
 	assert.Equal(t, `path "nested.key": expected object, found "string"`, err.Error())
 }
 
+func TestOrderedMapGetNested_NativeMap(t *testing.T) {
+	t.Parallel()
+	root := New()
+	root.Set(`native`, map[string]any{
+		`key`:    `value`,
+		`slice`:  []any{1, map[string]any{`foo`: `bar`}},
+		`nested`: FromPairs([]Pair{{Key: `x`, Value: 1}}),
+	})
+
+	// Found
+	assert.Equal(t, `value`, root.GetNestedOrNil(`native.key`))
+	assert.Equal(t, `bar`, root.GetNestedOrNil(`native.slice[1].foo`))
+	assert.Equal(t, 1, root.GetNestedOrNil(`native.nested.x`))
+
+	// Not found
+	value, found, err := root.GetNested(`native.foo`)
+	assert.Nil(t, value)
+	assert.False(t, found)
+	assert.EqualError(t, err, `path "native.foo" not found`)
+
+	// Invalid type
+	_, found, err = root.GetNested(`native.key.foo`)
+	assert.True(t, found)
+	assert.EqualError(t, err, `path "native.key": expected object found "string"`)
+
+	// Set is consistent with Get
+	assert.NoError(t, root.SetNested(`native.key`, `modified`))
+	assert.NoError(t, root.SetNested(`native.slice[1].foo`, `baz`))
+	assert.NoError(t, root.SetNested(`native.new.key`, `created`))
+	assert.NoError(t, root.SetNested(`native.list[1]`, `item`))
+	assert.Equal(t, `modified`, root.GetNestedOrNil(`native.key`))
+	assert.Equal(t, `baz`, root.GetNestedOrNil(`native.slice[1].foo`))
+	assert.Equal(t, `created`, root.GetNestedOrNil(`native.new.key`))
+	assert.Equal(t, []any{nil, `item`}, root.GetNestedOrNil(`native.list`))
+	native, _ := root.Get(`native`)
+	assert.Equal(t, FromPairs([]Pair{{Key: `key`, Value: `created`}}), native.(map[string]any)[`new`])
+
+	// GetNestedAll is consistent with Get, keys of the native map are sorted
+	matches, err := root.GetNestedAll(`native.new.*`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{{Path: PathFromStr(`native.new.key`), Value: `created`}}, matches)
+	_, err = root.GetNestedAll(`native.*.foo`)
+	assert.EqualError(t, err, `path "native.key": expected object found "string"`)
+	matches, err = root.GetNestedAll(`native.*`)
+	assert.NoError(t, err)
+	var paths []string
+	for _, m := range matches {
+		paths = append(paths, m.Path.String())
+	}
+	assert.Equal(t, []string{`native.key`, `native.list`, `native.nested`, `native.new`, `native.slice`}, paths)
+
+	// Rename is consistent with Get
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`native`), MapKeyStep(`key`)}, `renamed`))
+	assert.Equal(t, `modified`, root.GetNestedOrNil(`native.renamed`))
+	assert.EqualError(t, root.SetNestedPath(Path{MapStep(`native`), MapKeyStep(`key`)}, `foo`), `path "native[key].<key>" not found`)
+	assert.EqualError(t, root.SetNestedPath(Path{MapStep(`native`), MapKeyStep(`renamed`)}, `new`), `path "native[renamed].<key>": key "new" already exists`)
+
+	// Delete is consistent with Get
+	assert.NoError(t, root.DeleteNested(`native.renamed`))
+	assert.NoError(t, root.DeleteNested(`native.slice[0]`))
+	assert.Nil(t, root.GetNestedOrNil(`native.renamed`))
+	assert.Equal(t, []any{map[string]any{`foo`: `baz`}}, root.GetNestedOrNil(`native.slice`))
+	assert.EqualError(t, root.DeleteNested(`native.missing`), `path "native.missing" not found`)
+}
+
+func TestOrderedMapGetNestedAll(t *testing.T) {
+	t.Parallel()
+	input := `
+{
+  "parameters": {
+    "tables": [
+      {"id": "in.c-bucket.table1", "columns": ["a", "b"]},
+      {"name": "without id"},
+      {"id": "in.c-bucket.table2", "columns": ["c"]}
+    ],
+    "files": {
+      "file1": {"tags": ["x"]},
+      "file2": {"tags": ["y", "z"]}
+    }
+  }
+}
+`
+	root := New()
+	assert.NoError(t, json.Unmarshal([]byte(input), root))
+
+	// Wildcard in a slice position
+	matches, err := root.GetNestedAll(`parameters.tables[*].id`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{
+		{Path: PathFromStr(`parameters.tables[0].id`), Value: "in.c-bucket.table1"},
+		{Path: PathFromStr(`parameters.tables[2].id`), Value: "in.c-bucket.table2"},
+	}, matches)
+
+	// Wildcard in a map position
+	matches, err = root.GetNestedAll(`parameters.files.*.tags[0]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{
+		{Path: PathFromStr(`parameters.files.file1.tags[0]`), Value: "x"},
+		{Path: PathFromStr(`parameters.files.file2.tags[0]`), Value: "y"},
+	}, matches)
+
+	// Multiple wildcards
+	matches, err = root.GetNestedAll(`parameters.files.*.tags[*]`)
+	assert.NoError(t, err)
+	var paths []string
+	for _, match := range matches {
+		paths = append(paths, match.Path.String())
+	}
+	assert.Equal(t, []string{
+		`parameters.files.file1.tags[0]`,
+		`parameters.files.file2.tags[0]`,
+		`parameters.files.file2.tags[1]`,
+	}, paths)
+
+	// No match
+	matches, err = root.GetNestedAll(`parameters.missing[*]`)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	// Invalid: wildcard slice step on a map
+	_, err = root.GetNestedAll(`parameters.files[*]`)
+	assert.Error(t, err)
+	assert.Equal(t, `path "parameters.files": expected array found "*orderedmap.OrderedMap"`, err.Error())
+
+	// Invalid: wildcard map step on a slice
+	_, err = root.GetNestedAll(`parameters.tables.*`)
+	assert.Error(t, err)
+	assert.Equal(t, `path "parameters.tables": expected object found "[]interface {}"`, err.Error())
+
+	// Invalid: empty path
+	_, err = root.GetNestedAll(``)
+	assert.Error(t, err)
+	assert.Equal(t, `path cannot be empty`, err.Error())
+}
+
 func TestOrderedMapSetNested(t *testing.T) {
 	t.Parallel()
 	root := New()
@@ -371,7 +812,7 @@ func TestOrderedMapSetNested(t *testing.T) {
 	// Invalid: unknown step type
 	err = root.SetNestedPath(Path{MapStep("test"), MapKeyStep("test")}, 1)
 	assert.Error(t, err)
-	assert.Equal(t, `path "test[test].<key>": last key must be MapStep of SliceStep, found "orderedmap.MapKeyStep"`, err.Error())
+	assert.Equal(t, `path "test[test].<key>": new key must be string, found "int"`, err.Error())
 	err = root.SetNestedPath(Path{MapStep("test"), MapKeyStep("test"), MapStep("test")}, 1)
 	assert.Error(t, err)
 	assert.Equal(t, `unexpected type "orderedmap.MapKeyStep"`, err.Error())
@@ -438,8 +879,199 @@ func TestOrderedMapSetNested(t *testing.T) {
       ]
     ]
   ],
-  "str": "value",
-  "test": []
+  "str": "value"
+}
+`
+	jsonBytes, err := json.MarshalIndent(root, "", "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(expected), string(jsonBytes))
+}
+
+func TestOrderedMapSetNestedStrict(t *testing.T) {
+	t.Parallel()
+	root := New()
+
+	// Lenient variant fills the gap with nil values
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`lenient`), SliceStep(2)}, 1))
+	assert.Equal(t, []any{nil, nil, 1}, root.GetOrNil(`lenient`))
+
+	// Strict variant allows setting an existing index or appending
+	assert.NoError(t, root.SetNestedPathStrict(Path{MapStep(`strict`), SliceStep(0)}, 1))
+	assert.NoError(t, root.SetNestedPathStrict(Path{MapStep(`strict`), SliceStep(1)}, 2))
+	assert.NoError(t, root.SetNestedStrict(`strict[0]`, 3))
+	assert.NoError(t, root.SetNestedStrict(`nested[0][0]`, 4))
+	assert.Equal(t, []any{3, 2}, root.GetOrNil(`strict`))
+	assert.Equal(t, []any{[]any{4}}, root.GetOrNil(`nested`))
+
+	// Invalid: gap in the last step
+	err := root.SetNestedPathStrict(Path{MapStep(`strict`), SliceStep(5)}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path "strict[5]": array key is out of range, array length is 2`, err.Error())
+
+	// Invalid: gap in a parent step
+	err = root.SetNestedStrict(`nested[3].key`, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path "nested[3]": array key is out of range, array length is 1`, err.Error())
+	assert.Equal(t, []any{3, 2}, root.GetOrNil(`strict`))
+	assert.Equal(t, []any{[]any{4}}, root.GetOrNil(`nested`))
+
+	// Failed set doesn't create intermediate values
+	empty := New()
+	assert.EqualError(t, empty.SetNestedStrict(`x[3]`, 1), `path "x[3]": array key is out of range, array length is 0`)
+	assert.EqualError(t, empty.SetNestedStrict(`x.y[0][2]`, 1), `path "x.y[0][2]": array key is out of range, array length is 0`)
+	assert.EqualError(t, empty.SetNestedPath(Path{MapStep(`x`), AppendStep{}, SliceStep(-1)}, 1), `path "x[0][-1]": array key can't be negative`)
+	assert.EqualError(t, root.SetNestedStrict(`nested[1][1]`, 1), `path "nested[1][1]": array key is out of range, array length is 0`)
+	assert.Equal(t, `{}`, empty.String())
+	assert.Equal(t, []any{[]any{4}}, root.GetOrNil(`nested`))
+}
+
+func TestOrderedMapSetNestedPath_AppendStep(t *testing.T) {
+	t.Parallel()
+	root := New()
+
+	// Chained appends, the slice is created
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`list`), AppendStep{}}, 1))
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`list`), AppendStep{}}, 2))
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`list`), AppendStep{}}, 3))
+
+	// Nested AppendSteps
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`nested`), AppendStep{}, MapStep(`key`)}, `a`))
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`nested`), SliceStep(0), MapStep(`items`), AppendStep{}, AppendStep{}}, `b`))
+	assert.NoError(t, root.SetNestedPath(Path{MapStep(`nested`), SliceStep(0), MapStep(`items`), SliceStep(0), AppendStep{}}, `c`))
+
+	// Invalid: AppendStep on a map
+	err := root.SetNestedPath(Path{MapStep(`nested`), SliceStep(0), AppendStep{}}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path "nested[0][]": expected array found "*orderedmap.OrderedMap"`, err.Error())
+	err = root.SetNestedPath(Path{MapStep(`nested`), SliceStep(0), AppendStep{}, MapStep(`key`)}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path "nested[0][]": expected array found "*orderedmap.OrderedMap"`, err.Error())
+
+	// Invalid: first step is AppendStep
+	err = root.SetNestedPath(Path{AppendStep{}, MapStep(`key`)}, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `first key must be MapStep, found "orderedmap.AppendStep"`, err.Error())
+
+	expected := `
+{
+  "list": [
+    1,
+    2,
+    3
+  ],
+  "nested": [
+    {
+      "key": "a",
+      "items": [
+        [
+          "b",
+          "c"
+        ]
+      ]
+    }
+  ]
+}
+`
+	jsonBytes, err := json.MarshalIndent(root, "", "  ")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(expected), string(jsonBytes))
+}
+
+func TestOrderedMapSetNestedPath_RenameKey(t *testing.T) {
+	t.Parallel()
+	root := New()
+	assert.NoError(t, json.Unmarshal([]byte(`{"m":{"a":1,"old":{"x":2},"c":3},"s":[]}`), root))
+
+	// Rename middle key, the position and the value are preserved
+	assert.NoError(t, root.SetNestedPath(Path{MapStep("m"), MapKeyStep("old")}, "new"))
+	out, err := json.Marshal(root)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"m":{"a":1,"new":{"x":2},"c":3},"s":[]}`, string(out))
+
+	// Rename top level key
+	assert.NoError(t, root.SetNestedPath(Path{MapKeyStep("m")}, "map"))
+	assert.Equal(t, []string{"map", "s"}, root.Keys())
+
+	// Rename to the same key
+	assert.NoError(t, root.SetNestedPath(Path{MapKeyStep("s")}, "s"))
+	assert.Equal(t, []string{"map", "s"}, root.Keys())
+
+	// Invalid: missing key
+	err = root.SetNestedPath(Path{MapStep("map"), MapKeyStep("missing")}, "new")
+	assert.Error(t, err)
+	assert.Equal(t, `path "map[missing].<key>" not found`, err.Error())
+
+	// Invalid: missing parent, it is not created
+	err = root.SetNestedPath(Path{MapStep("missing"), MapKeyStep("old")}, "new")
+	assert.Error(t, err)
+	assert.Equal(t, `path "missing" not found`, err.Error())
+	assert.Equal(t, []string{"map", "s"}, root.Keys())
+
+	// Invalid: new key already exists
+	err = root.SetNestedPath(Path{MapStep("map"), MapKeyStep("a")}, "c")
+	assert.Error(t, err)
+	assert.Equal(t, `path "map[a].<key>": key "c" already exists`, err.Error())
+
+	// Invalid: parent is not a map
+	err = root.SetNestedPath(Path{MapStep("s"), MapKeyStep("a")}, "b")
+	assert.Error(t, err)
+	assert.Equal(t, `path "s": expected object found "[]interface {}"`, err.Error())
+}
+
+func TestOrderedMapAppendNested(t *testing.T) {
+	t.Parallel()
+	root := New()
+	assert.NoError(t, root.SetNested(`nested.map`, New()))
+	assert.NoError(t, root.SetNested(`nested.slice`, []any{1}))
+
+	// Append to a new path
+	assert.NoError(t, root.AppendNested(`new.slice`, 1, 2))
+	assert.NoError(t, root.AppendNestedPath(Path{MapStep(`new`), MapStep(`slice`)}, 3))
+
+	// Append to an existing slice
+	assert.NoError(t, root.AppendNested(`nested.slice`, 2))
+	assert.NoError(t, root.AppendNested(`nested.slice`))
+
+	// Append to a nested slice in a slice
+	assert.NoError(t, root.AppendNested(`new.slice2[1]`, `a`))
+
+	// Invalid: path is a map
+	err := root.AppendNested(`nested.map`, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path "nested.map": expected array found "*orderedmap.OrderedMap"`, err.Error())
+
+	// Invalid: parent is a slice
+	err = root.AppendNested(`nested.slice.key`, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path "nested.slice": expected object found "[]interface {}"`, err.Error())
+
+	// Invalid: empty path
+	err = root.AppendNested(``, 1)
+	assert.Error(t, err)
+	assert.Equal(t, `path cannot be empty`, err.Error())
+
+	expected := `
+{
+  "nested": {
+    "map": {},
+    "slice": [
+      1,
+      2
+    ]
+  },
+  "new": {
+    "slice": [
+      1,
+      2,
+      3
+    ],
+    "slice2": [
+      null,
+      [
+        "a"
+      ]
+    ]
+  }
 }
 `
 	jsonBytes, err := json.MarshalIndent(root, "", "  ")
@@ -465,6 +1097,66 @@ func TestFromPairs(t *testing.T) {
 	assert.Equal(t, strings.TrimSpace(expected), string(jsonBytes))
 }
 
+func TestOrderedMap_TransformValues(t *testing.T) {
+	t.Parallel()
+	root := New()
+	assert.NoError(t, json.Unmarshal([]byte(`
+{
+  "name": "config",
+  "#password": "secret1",
+  "nested": {"#token": "secret2", "count": 3, "list": ["#item", "item", {"#key": "secret3"}]},
+  "#map": {"foo": "bar"}
+}
+`), root))
+
+	// Redact all string leaves under a key starting with "#", and all slice items starting with "#"
+	var paths []string
+	root.TransformValues(func(path Path, value any) (any, bool) {
+		paths = append(paths, path.String())
+		str, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		if step, ok := path.Last().(MapStep); ok && strings.HasPrefix(step.Key(), "#") {
+			return "*****", true
+		}
+		if strings.HasPrefix(str, "#") {
+			return "*****", true
+		}
+		return nil, false
+	})
+
+	out, err := json.Marshal(root)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"config","#password":"*****","nested":{"#token":"*****","count":3,"list":["*****","item",{"#key":"*****"}]},"#map":{"foo":"bar"}}`, string(out))
+	assert.Equal(t, []string{
+		"name",
+		"#password",
+		"nested",
+		"nested.#token",
+		"nested.count",
+		"nested.list",
+		"nested.list[0]",
+		"nested.list[1]",
+		"nested.list[2]",
+		"nested.list[2].#key",
+		"#map",
+		"#map.foo",
+	}, paths)
+
+	// Replaced value is visited
+	root.TransformValues(func(path Path, value any) (any, bool) {
+		if path.String() == "#map" {
+			return []any{"#replaced"}, true
+		}
+		if path.String() == "#map[0]" {
+			return "visited", true
+		}
+		return nil, false
+	})
+	assert.Equal(t, []any{"visited"}, root.GetNestedOrNil(`#map`))
+}
+
 func TestOrderedMap_VisitAllRecursive(t *testing.T) {
 	t.Parallel()
 	input := `
@@ -535,3 +1227,114 @@ path=str, parent=*orderedmap.OrderedMap, value=string
 	})
 	assert.Equal(t, strings.TrimSpace(expected), strings.Join(visited, "\n"))
 }
+
+func TestOrderedMap_CountLeaves(t *testing.T) {
+	t.Parallel()
+	m := New()
+	assert.NoError(t, json.Unmarshal([]byte(multiLevelJSON), m))
+	assert.Equal(t, 12, m.CountLeaves())
+
+	// Empty map and slice are not leaves
+	m = New()
+	assert.Equal(t, 0, m.CountLeaves())
+	m.Set(`map`, New())
+	m.Set(`slice`, []any{})
+	assert.Equal(t, 0, m.CountLeaves())
+	m.Set(`slice`, []any{nil, 1})
+	assert.Equal(t, 2, m.CountLeaves())
+}
+
+func TestOrderedMap_MaxDepth(t *testing.T) {
+	t.Parallel()
+	m := New()
+	assert.NoError(t, json.Unmarshal([]byte(multiLevelJSON), m))
+	assert.Equal(t, 7, m.MaxDepth())
+
+	m = New()
+	assert.Equal(t, 1, m.MaxDepth())
+	m.Set(`key`, `value`)
+	assert.Equal(t, 1, m.MaxDepth())
+	m.Set(`slice`, []any{New()})
+	assert.Equal(t, 3, m.MaxDepth())
+}
+
+const multiLevelJSON = `
+{
+    "foo1": "bar1",
+    "foo2": "bar2",
+    "nested1": {
+        "foo3": "bar3",
+        "foo4": "bar4",
+        "nested2": {
+            "key": "value"
+        },
+        "slice": [
+            123,
+            "abc",
+            {
+                "nested3": {
+                    "foo5": "bar5"
+                }
+            },
+            {
+                "subSlice": [
+                    456,
+                    "def",
+                    {
+                        "nested4": {
+                            "foo6": "bar6"
+                        }
+                    }
+                ]
+            }
+        ]
+    },
+    "str": "value"
+}
+`
+
+func TestDeleteNested(t *testing.T) {
+	t.Parallel()
+	m, err := FromJSON([]byte(`{"foo":"bar","nested":{"a":1,"b":2},"list":[1,{"c":3},3]}`))
+	assert.NoError(t, err)
+	original := m.GetOrNil("list").([]any)
+
+	assert.NoError(t, m.DeleteNested("foo"))
+	assert.NoError(t, m.DeleteNested("nested.a"))
+	assert.NoError(t, m.DeleteNested("list[1]"))
+	assert.Equal(t, `{"nested":{"b":2},"list":[1,3]}`, m.String())
+
+	// The original slice is not modified
+	assert.Len(t, original, 3)
+
+	// Errors
+	assert.EqualError(t, m.DeleteNested("missing"), `path "missing" not found`)
+	assert.EqualError(t, m.DeleteNested("list[5]"), `path "list[5]" not found`)
+	assert.EqualError(t, m.DeleteNested("nested.b.c"), `path "nested.b": expected object found "float64"`)
+	assert.EqualError(t, m.DeleteNestedPath(Path{}), `path cannot be empty`)
+}
+
+func TestSetNestedCreatesIntermediateSlices(t *testing.T) {
+	t.Parallel()
+
+	// Slice after a new map key
+	m := New()
+	assert.NoError(t, m.SetNested("a.b[2].c", 1))
+	assert.Equal(t, `{"a":{"b":[null,null,{"c":1}]}}`, m.String())
+
+	// AppendStep after a new map key
+	m = New()
+	assert.NoError(t, m.SetNestedPath(Path{MapStep("a"), MapStep("b"), AppendStep{}, MapStep("c")}, 1))
+	assert.Equal(t, `{"a":{"b":[{"c":1}]}}`, m.String())
+
+	// Nested slices
+	m = New()
+	assert.NoError(t, m.SetNested("a.b[0][1].c", 1))
+	assert.Equal(t, `{"a":{"b":[[null,{"c":1}]]}}`, m.String())
+
+	// Strict mode creates a new slice only from the index 0
+	m = New()
+	assert.NoError(t, m.SetNestedStrict("a.b[0].c", 1))
+	assert.Equal(t, `{"a":{"b":[{"c":1}]}}`, m.String())
+	assert.EqualError(t, New().SetNestedStrict("a.b[2].c", 1), `path "a.b[2]": array key is out of range, array length is 0`)
+}
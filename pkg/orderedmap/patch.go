@@ -0,0 +1,178 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/keboola/go-utils/pkg/deepcopy"
+)
+
+const (
+	PatchOpAdd     = "add"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+	PatchOpMove    = "move"
+	PatchOpCopy    = "copy"
+	PatchOpTest    = "test"
+)
+
+// PatchOp is one operation of the JSON Patch, see RFC 6902.
+// Path and From are JSON Pointers, see RFC 6901. The root pointer "", the whole document, is not supported.
+// Value is always encoded, so an explicit null value is preserved.
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value"`
+}
+
+// ApplyJSONPatch applies JSON Patch operations, see RFC 6902.
+// The patch is atomic, if an operation fails, including the "test" operation, the map is not modified.
+func (o *OrderedMap) ApplyJSONPatch(ops []PatchOp) error {
+	clone := o.Clone()
+	for i, op := range ops {
+		if err := clone.applyPatchOp(op); err != nil {
+			return fmt.Errorf(`json patch operation %d "%s" failed: %w`, i, op.Op, err)
+		}
+	}
+	*o = *clone
+	return nil
+}
+
+func (o *OrderedMap) applyPatchOp(op PatchOp) error {
+	path, err := o.pointerToPath(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case PatchOpAdd:
+		return o.patchAdd(path, deepcopy.Copy(op.Value))
+	case PatchOpRemove:
+		return o.DeleteNestedPath(path)
+	case PatchOpReplace:
+		if _, _, err := o.GetNestedPath(path); err != nil {
+			return err
+		}
+		return o.SetNestedPath(path, deepcopy.Copy(op.Value))
+	case PatchOpMove, PatchOpCopy:
+		from, err := o.pointerToPath(op.From)
+		if err != nil {
+			return err
+		}
+		value, _, err := o.GetNestedPath(from)
+		if err != nil {
+			return err
+		}
+		if op.Op == PatchOpCopy {
+			return o.patchAdd(path, deepcopy.Copy(value))
+		}
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return fmt.Errorf(`path "%s" cannot be moved into itself`, from)
+		}
+		if err := o.DeleteNestedPath(from); err != nil {
+			return err
+		}
+		// Target path must be resolved again, the source has been removed
+		if path, err = o.pointerToPath(op.Path); err != nil {
+			return err
+		}
+		return o.patchAdd(path, value)
+	case PatchOpTest:
+		value, _, err := o.GetNestedPath(path)
+		if err != nil {
+			return err
+		}
+		if !patchValuesEqual(value, op.Value) {
+			return fmt.Errorf(`path "%s": test failed, expected "%v" found "%v"`, path, op.Value, value)
+		}
+		return nil
+	default:
+		return fmt.Errorf(`unexpected operation "%s"`, op.Op)
+	}
+}
+
+// patchAdd sets a map key, or inserts a slice item, the parent must exist.
+func (o *OrderedMap) patchAdd(path Path, value any) error {
+	parentPath := path.WithoutLast()
+	if len(parentPath) == 0 {
+		return o.SetNestedPath(path, value)
+	}
+
+	parent, _, err := o.GetNestedPath(parentPath)
+	if err != nil {
+		return err
+	}
+
+	// Insert slice item, the following items are shifted
+	if key, ok := path.Last().(SliceStep); ok {
+		s, ok := parent.([]any)
+		if !ok {
+			return fmt.Errorf(`path "%s": expected array found "%T"`, parentPath, parent)
+		}
+		if key.Index() > len(s) {
+			return fmt.Errorf(`path "%s": array key is out of range, array length is %d`, path, len(s))
+		}
+		out := make([]any, 0, len(s)+1)
+		out = append(out, s[:key.Index()]...)
+		out = append(out, value)
+		out = append(out, s[key.Index():]...)
+		return o.SetNestedPath(parentPath, out)
+	}
+
+	return o.SetNestedPath(path, value)
+}
+
+// pointerToPath converts JSON Pointer to Path, see RFC 6901.
+// A numeric token is converted to SliceStep only if the current value is a slice, "-" is converted to AppendStep.
+func (o *OrderedMap) pointerToPath(pointer string) (Path, error) {
+	if pointer == "" {
+		return nil, fmt.Errorf(`root JSON pointer "" is not supported`)
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf(`invalid JSON pointer "%s": must start with "/"`, pointer)
+	}
+
+	out := make(Path, 0)
+	var current any = o
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if s, ok := current.([]any); ok {
+			if token == "-" {
+				out = append(out, AppendStep{})
+				current = nil
+				continue
+			}
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf(`invalid JSON pointer "%s": invalid array index "%s"`, pointer, token)
+			}
+			out = append(out, SliceStep(index))
+			current = nil
+			if index < len(s) {
+				current = s[index]
+			}
+			continue
+		}
+
+		out = append(out, MapStep(token))
+		if m, ok := current.(*OrderedMap); ok {
+			current = m.GetOrNil(token)
+		} else if m, ok := current.(map[string]any); ok {
+			current = m[token]
+		} else {
+			current = nil
+		}
+	}
+	return out, nil
+}
+
+// patchValuesEqual compares values deeply, the order of object keys is ignored.
+func patchValuesEqual(a, b any) bool {
+	aJSON, aErr := json.Marshal(convertToMap(a))
+	bJSON, bErr := json.Marshal(convertToMap(b))
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
@@ -0,0 +1,167 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func patchTestMap(t *testing.T) *OrderedMap {
+	t.Helper()
+	o, err := FromJSON([]byte(`{"foo":"bar","list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`))
+	assert.NoError(t, err)
+	return o
+}
+
+func TestOrderedMap_ApplyJSONPatch(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		ops      string
+		expected string
+	}{
+		{
+			name:     "add key",
+			ops:      `[{"op":"add","path":"/baz","value":{"x":true}}]`,
+			expected: `{"foo":"bar","list":[1,2,3],"nested":{"a/b":1,"m~n":2},"baz":{"x":true}}`,
+		},
+		{
+			name:     "add slice item",
+			ops:      `[{"op":"add","path":"/list/1","value":"new"},{"op":"add","path":"/list/-","value":4}]`,
+			expected: `{"foo":"bar","list":[1,"new",2,3,4],"nested":{"a/b":1,"m~n":2}}`,
+		},
+		{
+			name:     "remove",
+			ops:      `[{"op":"remove","path":"/list/0"},{"op":"remove","path":"/nested/a~1b"}]`,
+			expected: `{"foo":"bar","list":[2,3],"nested":{"m~n":2}}`,
+		},
+		{
+			name:     "replace",
+			ops:      `[{"op":"replace","path":"/foo","value":"baz"},{"op":"replace","path":"/nested/m~0n","value":[]}]`,
+			expected: `{"foo":"baz","list":[1,2,3],"nested":{"a/b":1,"m~n":[]}}`,
+		},
+		{
+			name:     "move",
+			ops:      `[{"op":"move","from":"/list/2","path":"/nested/last"},{"op":"move","from":"/foo","path":"/moved"}]`,
+			expected: `{"list":[1,2],"nested":{"a/b":1,"m~n":2,"last":3},"moved":"bar"}`,
+		},
+		{
+			name:     "copy",
+			ops:      `[{"op":"copy","from":"/nested","path":"/list/0"}]`,
+			expected: `{"foo":"bar","list":[{"a/b":1,"m~n":2},1,2,3],"nested":{"a/b":1,"m~n":2}}`,
+		},
+		{
+			name:     "test",
+			ops:      `[{"op":"test","path":"/nested","value":{"m~n":2,"a/b":1}},{"op":"test","path":"/list/1","value":2}]`,
+			expected: `{"foo":"bar","list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		var ops []PatchOp
+		assert.NoError(t, json.Unmarshal([]byte(tc.ops), &ops), tc.name)
+		o := patchTestMap(t)
+		assert.NoError(t, o.ApplyJSONPatch(ops), tc.name)
+		assert.Equal(t, tc.expected, o.String(), tc.name)
+	}
+}
+
+func TestOrderedMap_ApplyJSONPatch_CopyIsDeep(t *testing.T) {
+	t.Parallel()
+	o := patchTestMap(t)
+	assert.NoError(t, o.ApplyJSONPatch([]PatchOp{{Op: PatchOpCopy, From: "/nested", Path: "/copy"}}))
+	assert.NoError(t, o.SetNested("copy.x", 1))
+	assert.Equal(t, `{"foo":"bar","list":[1,2,3],"nested":{"a/b":1,"m~n":2},"copy":{"a/b":1,"m~n":2,"x":1}}`, o.String())
+}
+
+func TestOrderedMap_ApplyJSONPatch_NativeMap(t *testing.T) {
+	t.Parallel()
+	o := New()
+	o.Set("native", map[string]any{"a": 1, "b": []any{1, 2}, "c": 3})
+	assert.NoError(t, o.ApplyJSONPatch([]PatchOp{
+		{Op: PatchOpRemove, Path: "/native/a"},
+		{Op: PatchOpRemove, Path: "/native/b/0"},
+		{Op: PatchOpMove, From: "/native/c", Path: "/moved"},
+	}))
+	assert.Equal(t, `{"native":{"b":[2]},"moved":3}`, o.String())
+}
+
+func TestPatchOp_NullValue(t *testing.T) {
+	t.Parallel()
+	ops := []PatchOp{{Op: PatchOpAdd, Path: "/foo", Value: nil}, {Op: PatchOpTest, Path: "/foo", Value: nil}}
+	out, err := json.Marshal(ops)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"op":"add","path":"/foo","value":null},{"op":"test","path":"/foo","value":null}]`, string(out))
+
+	// Round trip
+	var decoded []PatchOp
+	assert.NoError(t, json.Unmarshal(out, &decoded))
+	assert.Equal(t, ops, decoded)
+	o := patchTestMap(t)
+	assert.NoError(t, o.ApplyJSONPatch(decoded))
+	assert.Equal(t, `{"foo":null,"list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`, o.String())
+}
+
+func TestOrderedMap_ApplyJSONPatch_Errors(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		ops      []PatchOp
+		expected string
+	}{
+		{
+			ops:      []PatchOp{{Op: PatchOpRemove, Path: "/foo"}, {Op: PatchOpTest, Path: "/list/0", Value: 2}},
+			expected: `json patch operation 1 "test" failed: path "list[0]": test failed, expected "2" found "1"`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpRemove, Path: "/missing"}},
+			expected: `json patch operation 0 "remove" failed: path "missing" not found`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpReplace, Path: "/missing", Value: 1}},
+			expected: `json patch operation 0 "replace" failed: path "missing" not found`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "/missing/key", Value: 1}},
+			expected: `json patch operation 0 "add" failed: path "missing" not found`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "/list/5", Value: 1}},
+			expected: `json patch operation 0 "add" failed: path "list[5]": array key is out of range, array length is 3`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "/list/x", Value: 1}},
+			expected: `json patch operation 0 "add" failed: invalid JSON pointer "/list/x": invalid array index "x"`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpMove, From: "/nested", Path: "/nested/sub"}},
+			expected: `json patch operation 0 "move" failed: path "nested" cannot be moved into itself`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "foo", Value: 1}},
+			expected: `json patch operation 0 "add" failed: invalid JSON pointer "foo": must start with "/"`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpReplace, Path: "", Value: map[string]any{}}},
+			expected: `json patch operation 0 "replace" failed: root JSON pointer "" is not supported`,
+		},
+		{
+			ops:      []PatchOp{{Op: PatchOpCopy, From: "", Path: "/copy"}},
+			expected: `json patch operation 0 "copy" failed: root JSON pointer "" is not supported`,
+		},
+		{
+			ops:      []PatchOp{{Op: "invalid", Path: "/foo"}},
+			expected: `json patch operation 0 "invalid" failed: unexpected operation "invalid"`,
+		},
+	}
+
+	for _, tc := range cases {
+		o := patchTestMap(t)
+		err := o.ApplyJSONPatch(tc.ops)
+		if assert.Error(t, err) {
+			assert.Equal(t, tc.expected, err.Error())
+		}
+		// The patch is atomic, the map is not modified
+		assert.Equal(t, patchTestMap(t).String(), o.String())
+	}
+}
@@ -26,6 +26,15 @@ type MapKeyStep string
 // SliceStep represents a slice index.
 type SliceStep int
 
+// AppendStep represents a new element appended to a slice, it is used with SetNestedPath.
+type AppendStep struct{}
+
+// anyKeyStep matches all keys of a map, it is used by GetNestedAll.
+type anyKeyStep struct{}
+
+// anyIndexStep matches all indexes of a slice, it is used by GetNestedAll.
+type anyIndexStep struct{}
+
 // PathFromStr converts string to Path.
 func PathFromStr(str string) Path {
 	parts := strings.FieldsFunc(str, func(r rune) bool {
@@ -51,6 +60,20 @@ func PathFromStr(str string) Path {
 	return out
 }
 
+// patternFromStr converts string with "*" wildcards to Path, eg. "parameters.tables[*].id".
+func patternFromStr(str string) Path {
+	out := PathFromStr(str)
+	for i, step := range out {
+		switch step {
+		case MapStep(`*`):
+			out[i] = anyKeyStep{}
+		case MapStep(`*]`):
+			out[i] = anyIndexStep{}
+		}
+	}
+	return out
+}
+
 func (v Path) String() string {
 	parts := make([]string, 0)
 	for _, step := range v {
@@ -128,3 +151,15 @@ func (v SliceStep) Index() int {
 func (v SliceStep) String() string {
 	return fmt.Sprintf("[%d]", int(v))
 }
+
+func (v AppendStep) String() string {
+	return "[]"
+}
+
+func (v anyKeyStep) String() string {
+	return "*"
+}
+
+func (v anyIndexStep) String() string {
+	return "[*]"
+}
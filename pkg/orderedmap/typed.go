@@ -0,0 +1,136 @@
+package orderedmap
+
+import (
+	"fmt"
+	"math"
+)
+
+// GetNestedString returns nested string by path as string.
+func (o *OrderedMap) GetNestedString(path string) (value string, found bool, err error) {
+	return o.GetNestedPathString(PathFromStr(path))
+}
+
+// GetNestedPathString returns nested string by Path.
+func (o *OrderedMap) GetNestedPathString(path Path) (value string, found bool, err error) {
+	return getNestedTyped(o, path, "string", func(v any) (string, bool) {
+		s, ok := v.(string)
+		return s, ok
+	})
+}
+
+// GetNestedInt returns nested integer by path as string.
+// A float value without a fractional part is accepted too, JSON numbers are decoded as float64.
+func (o *OrderedMap) GetNestedInt(path string) (value int, found bool, err error) {
+	return o.GetNestedPathInt(PathFromStr(path))
+}
+
+// GetNestedPathInt returns nested integer by Path.
+// A float value without a fractional part is accepted too, JSON numbers are decoded as float64.
+func (o *OrderedMap) GetNestedPathInt(path Path) (value int, found bool, err error) {
+	return getNestedTyped(o, path, "integer", toInt)
+}
+
+// GetNestedFloat returns nested float by path as string, integer values are converted.
+func (o *OrderedMap) GetNestedFloat(path string) (value float64, found bool, err error) {
+	return o.GetNestedPathFloat(PathFromStr(path))
+}
+
+// GetNestedPathFloat returns nested float by Path, integer values are converted.
+func (o *OrderedMap) GetNestedPathFloat(path Path) (value float64, found bool, err error) {
+	return getNestedTyped(o, path, "number", toFloat)
+}
+
+// GetNestedBool returns nested bool by path as string.
+func (o *OrderedMap) GetNestedBool(path string) (value bool, found bool, err error) {
+	return o.GetNestedPathBool(PathFromStr(path))
+}
+
+// GetNestedPathBool returns nested bool by Path.
+func (o *OrderedMap) GetNestedPathBool(path Path) (value bool, found bool, err error) {
+	return getNestedTyped(o, path, "bool", func(v any) (bool, bool) {
+		b, ok := v.(bool)
+		return b, ok
+	})
+}
+
+// GetNestedStringOr returns nested string by path as string, or the default value if it is not found or it has a different type.
+func (o *OrderedMap) GetNestedStringOr(path string, def string) string {
+	if value, found, err := o.GetNestedString(path); found && err == nil {
+		return value
+	}
+	return def
+}
+
+// GetNestedIntOr returns nested integer by path as string, or the default value if it is not found or it has a different type.
+func (o *OrderedMap) GetNestedIntOr(path string, def int) int {
+	if value, found, err := o.GetNestedInt(path); found && err == nil {
+		return value
+	}
+	return def
+}
+
+// GetNestedFloatOr returns nested float by path as string, or the default value if it is not found or it has a different type.
+func (o *OrderedMap) GetNestedFloatOr(path string, def float64) float64 {
+	if value, found, err := o.GetNestedFloat(path); found && err == nil {
+		return value
+	}
+	return def
+}
+
+// GetNestedBoolOr returns nested bool by path as string, or the default value if it is not found or it has a different type.
+func (o *OrderedMap) GetNestedBoolOr(path string, def bool) bool {
+	if value, found, err := o.GetNestedBool(path); found && err == nil {
+		return value
+	}
+	return def
+}
+
+func getNestedTyped[T any](o *OrderedMap, path Path, typeName string, convert func(v any) (T, bool)) (value T, found bool, err error) {
+	raw, found, err := o.GetNestedPath(path)
+	if !found {
+		return value, false, nil
+	} else if err != nil {
+		return value, true, err
+	}
+	if v, ok := convert(raw); ok {
+		return v, true, nil
+	}
+	return value, true, fmt.Errorf(`path "%s": expected %s, found "%T"`, path, typeName, raw)
+}
+
+func toInt(v any) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt && v <= math.MaxInt {
+			return int(v), true
+		}
+	case float32:
+		if float64(v) == math.Trunc(float64(v)) {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package orderedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func typedTestMap(t *testing.T) *OrderedMap {
+	t.Helper()
+	o, err := FromJSON([]byte(`{"str":"foo","int":123,"float":1.5,"bool":true,"nested":{"list":["a",2,false]}}`))
+	assert.NoError(t, err)
+	o.Set("nativeInt", int64(456))
+	return o
+}
+
+func TestOrderedMap_GetNestedTyped(t *testing.T) {
+	t.Parallel()
+	o := typedTestMap(t)
+
+	// Present
+	s, found, err := o.GetNestedString("nested.list[0]")
+	assert.Equal(t, "a", s)
+	assert.True(t, found)
+	assert.NoError(t, err)
+
+	i, found, err := o.GetNestedInt("int")
+	assert.Equal(t, 123, i)
+	assert.True(t, found)
+	assert.NoError(t, err)
+
+	i, _, err = o.GetNestedPathInt(Path{MapStep("nativeInt")})
+	assert.Equal(t, 456, i)
+	assert.NoError(t, err)
+
+	f, _, err := o.GetNestedFloat("float")
+	assert.Equal(t, 1.5, f)
+	assert.NoError(t, err)
+
+	f, _, err = o.GetNestedFloat("nativeInt")
+	assert.Equal(t, 456.0, f)
+	assert.NoError(t, err)
+
+	b, _, err := o.GetNestedBool("nested.list[2]")
+	assert.False(t, b)
+	assert.NoError(t, err)
+
+	// Missing
+	s, found, err = o.GetNestedString("missing")
+	assert.Equal(t, "", s)
+	assert.False(t, found)
+	assert.NoError(t, err)
+
+	// Wrong type
+	_, found, err = o.GetNestedInt("float")
+	assert.True(t, found)
+	assert.EqualError(t, err, `path "float": expected integer, found "float64"`)
+
+	_, found, err = o.GetNestedString("nested")
+	assert.True(t, found)
+	assert.EqualError(t, err, `path "nested": expected string, found "*orderedmap.OrderedMap"`)
+
+	_, found, err = o.GetNestedBool("str.key")
+	assert.True(t, found)
+	assert.EqualError(t, err, `path "str": expected object found "string"`)
+}
+
+func TestOrderedMap_GetNestedOr(t *testing.T) {
+	t.Parallel()
+	o := typedTestMap(t)
+
+	// Present
+	assert.Equal(t, "foo", o.GetNestedStringOr("str", "default"))
+	assert.Equal(t, 123, o.GetNestedIntOr("int", 1))
+	assert.Equal(t, 1.5, o.GetNestedFloatOr("float", 1))
+	assert.Equal(t, true, o.GetNestedBoolOr("bool", false))
+	assert.Equal(t, 2, o.GetNestedIntOr("nested.list[1]", 1))
+
+	// Missing
+	assert.Equal(t, "default", o.GetNestedStringOr("missing", "default"))
+	assert.Equal(t, 1, o.GetNestedIntOr("nested.list[10]", 1))
+	assert.Equal(t, 2.5, o.GetNestedFloatOr("nested.missing", 2.5))
+	assert.Equal(t, true, o.GetNestedBoolOr("missing", true))
+
+	// Wrong type
+	assert.Equal(t, "default", o.GetNestedStringOr("int", "default"))
+	assert.Equal(t, 1, o.GetNestedIntOr("float", 1))
+	assert.Equal(t, 2.5, o.GetNestedFloatOr("str", 2.5))
+	assert.Equal(t, true, o.GetNestedBoolOr("str.key", true))
+}
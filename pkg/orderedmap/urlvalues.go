@@ -0,0 +1,106 @@
+package orderedmap
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToURLValues flattens the OrderedMap to url.Values, for example to build a query string or a form body.
+// Nested keys use the PHP-style bracket notation, for example "a[b][0]".
+// Keys of a nested native map[string]any are sorted, the map has no order.
+// Values are converted to strings by fmt.Sprint, nil is converted to an empty string.
+// Empty nested maps and slices are omitted, they have no representation in url.Values.
+func (o *OrderedMap) ToURLValues() url.Values {
+	out := make(url.Values)
+	for _, key := range o.keys {
+		toURLValues(out, key, o.values[key])
+	}
+	return out
+}
+
+func toURLValues(out url.Values, key string, value any) {
+	switch v := value.(type) {
+	case *OrderedMap:
+		for _, k := range v.keys {
+			toURLValues(out, key+"["+k+"]", v.values[k])
+		}
+	case OrderedMap:
+		toURLValues(out, key, &v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			toURLValues(out, key+"["+k+"]", v[k])
+		}
+	case []any:
+		for i, item := range v {
+			toURLValues(out, key+"["+strconv.Itoa(i)+"]", item)
+		}
+	case []string:
+		for i, item := range v {
+			out.Set(key+"["+strconv.Itoa(i)+"]", item)
+		}
+	case nil:
+		out.Set(key, "")
+	default:
+		out.Set(key, fmt.Sprint(v))
+	}
+}
+
+// FromURLValues creates the OrderedMap from url.Values, it is the inverse of the ToURLValues method.
+// Keys in the PHP-style bracket notation, for example "a[b][0]", are converted to nested values.
+// A numeric bracket key is a slice index, an empty bracket key "a[]" appends each value to a slice.
+// If a key has multiple values, the last value wins.
+// Keys are processed in alphabetical order, url.Values has no order.
+func FromURLValues(values url.Values) (*OrderedMap, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	o := New()
+	for _, key := range keys {
+		path := urlKeyToPath(key)
+		for _, value := range values[key] {
+			if err := o.SetNestedPath(path, value); err != nil {
+				return nil, fmt.Errorf(`cannot set key "%s": %w`, key, err)
+			}
+		}
+	}
+	return o, nil
+}
+
+// urlKeyToPath converts the bracket notation, for example "a[b][0]", to Path.
+// A malformed key is used as a map key as it is.
+func urlKeyToPath(key string) Path {
+	name, rest, found := strings.Cut(key, "[")
+	if !found || name == "" {
+		return Path{MapStep(key)}
+	}
+
+	path := Path{MapStep(name)}
+	rest = "[" + rest
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if !strings.HasPrefix(rest, "[") || end == -1 {
+			return Path{MapStep(key)}
+		}
+		step := rest[1:end]
+		rest = rest[end+1:]
+		if step == "" {
+			path = append(path, AppendStep{})
+		} else if index, err := strconv.Atoi(step); err == nil && index >= 0 && step == strconv.Itoa(index) {
+			path = append(path, SliceStep(index))
+		} else {
+			path = append(path, MapStep(step))
+		}
+	}
+	return path
+}
@@ -0,0 +1,76 @@
+package orderedmap
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMap_ToURLValues(t *testing.T) {
+	t.Parallel()
+	o := FromPairs([]Pair{
+		{Key: "name", Value: "foo"},
+		{Key: "count", Value: 3},
+		{Key: "enabled", Value: true},
+		{Key: "empty", Value: nil},
+		{Key: "a", Value: FromPairs([]Pair{
+			{Key: "b", Value: []any{"x", "y"}},
+			{Key: "c", Value: "z"},
+		})},
+		{Key: "tags", Value: []string{"t1", "t2"}},
+		{Key: "native", Value: map[string]any{"y": []any{map[string]any{"k": "v"}}, "x": 1}},
+	})
+	assert.Equal(t, url.Values{
+		"name":            {"foo"},
+		"count":           {"3"},
+		"enabled":         {"true"},
+		"empty":           {""},
+		"a[b][0]":         {"x"},
+		"a[b][1]":         {"y"},
+		"a[c]":            {"z"},
+		"tags[0]":         {"t1"},
+		"tags[1]":         {"t2"},
+		"native[x]":       {"1"},
+		"native[y][0][k]": {"v"},
+	}, o.ToURLValues())
+	assert.Equal(t, "a%5Bb%5D%5B0%5D=x&a%5Bb%5D%5B1%5D=y&a%5Bc%5D=z&count=3&empty=&enabled=true&name=foo&native%5Bx%5D=1&native%5By%5D%5B0%5D%5Bk%5D=v&tags%5B0%5D=t1&tags%5B1%5D=t2", o.ToURLValues().Encode())
+}
+
+func TestFromURLValues(t *testing.T) {
+	t.Parallel()
+	values, err := url.ParseQuery("z=1&a[c][]=x&a[c][]=y&a[b][1]=q&a[b][0]=p&a[d]=v1&a[d]=v2&b[=malformed")
+	assert.NoError(t, err)
+	o, err := FromURLValues(values)
+	assert.NoError(t, err)
+	assert.Equal(t, FromPairs([]Pair{
+		{Key: "a", Value: FromPairs([]Pair{
+			{Key: "b", Value: []any{"p", "q"}},
+			{Key: "c", Value: []any{"x", "y"}},
+			{Key: "d", Value: "v2"},
+		})},
+		{Key: "b[", Value: "malformed"},
+		{Key: "z", Value: "1"},
+	}), o)
+
+	// Conflicting keys
+	_, err = FromURLValues(url.Values{"a": {"1"}, "a[b]": {"2"}})
+	assert.EqualError(t, err, `cannot set key "a[b]": path "a.b": expected object found "string"`)
+}
+
+func TestURLValues_RoundTrip(t *testing.T) {
+	t.Parallel()
+	o := FromPairs([]Pair{
+		{Key: "a", Value: FromPairs([]Pair{
+			{Key: "b", Value: []any{"x", "y"}},
+			{Key: "c", Value: "z"},
+		})},
+		{Key: "d", Value: FromPairs([]Pair{
+			{Key: "e", Value: []any{FromPairs([]Pair{{Key: "f", Value: "1"}}), FromPairs([]Pair{{Key: "f", Value: "2"}})}},
+		})},
+		{Key: "name", Value: "foo"},
+	})
+	result, err := FromURLValues(o.ToURLValues())
+	assert.NoError(t, err)
+	assert.Equal(t, o, result)
+}
@@ -1,30 +1,54 @@
 package orderedmap
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// YAMLStyleOptions for the MarshalYAMLStyle method.
+// The zero style means the default style. A style already chosen by the encoder, for example quotes for the "true" string, is kept.
+// Values of the *yaml.Node type are kept unchanged.
+type YAMLStyleOptions struct {
+	// Strings is style of string scalars, including keys, eg. yaml.DoubleQuotedStyle.
+	Strings yaml.Style
+	// Maps is style of maps, eg. yaml.FlowStyle.
+	Maps yaml.Style
+	// Sequences is style of sequences, eg. yaml.FlowStyle.
+	Sequences yaml.Style
+}
+
 func (o *OrderedMap) MarshalYAML() (any, error) {
-	node := &yaml.Node{Kind: yaml.MappingNode}
+	return o.MarshalYAMLStyle(YAMLStyleOptions{})
+}
+
+// MarshalYAMLStyle encodes OrderedMap to YAML node, styles of the nodes are set according to the options.
+func (o *OrderedMap) MarshalYAMLStyle(opts YAMLStyleOptions) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Style: opts.Maps}
 	for _, key := range o.Keys() {
 		// Encode key
 		keyNode := &yaml.Node{Kind: yaml.MappingNode}
 		if err := keyNode.Encode(key); err != nil {
 			return nil, err
 		}
+		opts.applyScalarStyle(keyNode)
 
 		// Encode value
 		value, _ := o.Get(key)
-		valueNode, err := encodeYamlValue(value)
+		valueNode, err := encodeYamlValue(value, opts)
 		if err != nil {
 			return nil, err
 		}
 
+		// Set comment of the key, see SetComment
+		keyNode.HeadComment = o.Comment(key)
+
 		// Move head comment from the value to the key node, if any
 		if valueNode.HeadComment != "" {
-			keyNode.HeadComment = valueNode.HeadComment
+			keyNode.HeadComment = strings.TrimPrefix(keyNode.HeadComment+"\n"+valueNode.HeadComment, "\n")
 			valueNode.HeadComment = ""
 		}
 
@@ -73,20 +97,36 @@ func (o *OrderedMap) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
-func encodeYamlValue(value any) (out *yaml.Node, err error) {
+// DecodeYAMLAny decodes YAML node with any root: a map is decoded to *OrderedMap, a sequence to []any, a scalar to its value.
+// Nested maps are decoded to *OrderedMap too. Unlike OrderedMap.UnmarshalYAML, the root doesn't have to be a map.
+func DecodeYAMLAny(node *yaml.Node) (any, error) {
+	// Unwrap document, for example the result of yaml.Unmarshal to *yaml.Node
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		node = node.Content[0]
+	}
+
+	var out any
+	if err := decodeYamlValue(node, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func encodeYamlValue(value any, opts YAMLStyleOptions) (out *yaml.Node, err error) {
 	switch v := value.(type) {
 	case *yaml.Node:
 		return v, nil
 	case *OrderedMap:
-		if subNode, err := v.MarshalYAML(); err == nil {
-			return subNode.(*yaml.Node), nil
-		} else {
-			return nil, err
-		}
+		return v.MarshalYAMLStyle(opts)
+	case OrderedMap:
+		return v.MarshalYAMLStyle(opts)
 	case []any:
-		out = &yaml.Node{Kind: yaml.SequenceNode}
+		out = &yaml.Node{Kind: yaml.SequenceNode, Style: opts.Sequences}
 		for _, item := range v {
-			if subNode, err := encodeYamlValue(item); err == nil {
+			if subNode, err := encodeYamlValue(item, opts); err == nil {
 				out.Content = append(out.Content, subNode)
 			} else {
 				return nil, err
@@ -94,14 +134,67 @@ func encodeYamlValue(value any) (out *yaml.Node, err error) {
 		}
 		return out, nil
 	default:
+		// Value with custom JSON encoding is encoded the same way as in JSON, if it has no custom YAML or text encoding
+		if m, ok := value.(json.Marshaler); ok && !hasYamlEncoding(value) {
+			return encodeYamlFromJSON(m, opts)
+		}
 		out = &yaml.Node{Kind: yaml.ScalarNode}
 		if err := out.Encode(value); err != nil {
 			return nil, err
 		}
+		opts.applyScalarStyle(out)
 		return out, nil
 	}
 }
 
+// hasYamlEncoding returns true if the value has custom encoding supported by the YAML encoder.
+func hasYamlEncoding(value any) bool {
+	switch value.(type) {
+	case yaml.Marshaler, encoding.TextMarshaler:
+		return true
+	default:
+		return false
+	}
+}
+
+// encodeYamlFromJSON converts JSON output of the value to YAML node, JSON is a valid YAML.
+func encodeYamlFromJSON(value json.Marshaler, opts YAMLStyleOptions) (*yaml.Node, error) {
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(jsonBytes, doc); err != nil {
+		return nil, err
+	}
+	out := doc.Content[0]
+	resetYamlStyle(out, opts)
+	return out, nil
+}
+
+// resetYamlStyle replaces the JSON flow style and quotes by the styles from the options.
+func resetYamlStyle(node *yaml.Node, opts YAMLStyleOptions) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		node.Style = opts.Maps
+	case yaml.SequenceNode:
+		node.Style = opts.Sequences
+	case yaml.ScalarNode:
+		node.Style = 0
+		opts.applyScalarStyle(node)
+	}
+	for _, child := range node.Content {
+		resetYamlStyle(child, opts)
+	}
+}
+
+// applyScalarStyle sets style of a string scalar, if the style is not already set by the encoder.
+func (opts YAMLStyleOptions) applyScalarStyle(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && node.Style == 0 {
+		node.Style = opts.Strings
+	}
+}
+
 func decodeYamlValue(node *yaml.Node, out *any) error {
 	switch node.Tag {
 	case "!!map": // key-value map
@@ -2,6 +2,7 @@ package orderedmap
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -76,6 +77,56 @@ func TestOrderedMap_MarshalYAML_Blank(t *testing.T) {
 	assert.Equal(t, "{}\n", out.String())
 }
 
+func TestOrderedMap_MarshalYAMLStyle(t *testing.T) {
+	t.Parallel()
+	nested := New()
+	nested.Set("b", "x")
+	nested.Set("a", 1)
+	o := New()
+	o.Set("string", "value")
+	o.Set("bool", "true")
+	o.Set("map", nested)
+	o.Set("slice", []any{"x", 2})
+	o.Set("node", &yaml.Node{Kind: yaml.ScalarNode, Value: "plain"})
+
+	encode := func(opts YAMLStyleOptions) string {
+		node, err := o.MarshalYAMLStyle(opts)
+		assert.NoError(t, err)
+		var out bytes.Buffer
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		assert.NoError(t, encoder.Encode(node))
+		return out.String()
+	}
+
+	// Double-quoted strings
+	expected := `
+"string": "value"
+"bool": "true"
+"map":
+  "b": "x"
+  "a": 1
+"slice":
+  - "x"
+  - 2
+"node": plain
+`
+	assert.Equal(t, strings.TrimLeft(expected, "\n"), encode(YAMLStyleOptions{Strings: yaml.DoubleQuotedStyle}))
+
+	// Flow-style maps and sequences
+	expected = `
+{string: value, bool: "true", map: {b: x, a: 1}, slice: [x, 2], node: plain}
+`
+	assert.Equal(t, strings.TrimLeft(expected, "\n"), encode(YAMLStyleOptions{Maps: yaml.FlowStyle, Sequences: yaml.FlowStyle}))
+
+	// Default style, the same as MarshalYAML
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	assert.NoError(t, encoder.Encode(o))
+	assert.Equal(t, out.String(), encode(YAMLStyleOptions{}))
+}
+
 func TestOrderedMap_UnmarshalYAML(t *testing.T) {
 	t.Parallel()
 	in := `
@@ -371,3 +422,102 @@ func TestOrderedMap_UnmarshalYAML_Text(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "cannot unmarshal !!str `some text` into orderedmap", err.Error())
 }
+
+func TestDecodeYAMLAny(t *testing.T) {
+	t.Parallel()
+
+	// Sequence root
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte("- b: 1\n  a: 2\n- [x, y]\n- foo\n"), &node))
+	value, err := DecodeYAMLAny(&node)
+	assert.NoError(t, err)
+	item := New()
+	item.Set("b", 1)
+	item.Set("a", 2)
+	assert.Equal(t, []any{item, []any{"x", "y"}, "foo"}, value)
+
+	// Map root
+	node = yaml.Node{}
+	assert.NoError(t, yaml.Unmarshal([]byte("b: 1\na: 2\n"), &node))
+	value, err = DecodeYAMLAny(&node)
+	assert.NoError(t, err)
+	assert.Equal(t, item, value)
+
+	// Scalar root
+	node = yaml.Node{}
+	assert.NoError(t, yaml.Unmarshal([]byte("123"), &node))
+	value, err = DecodeYAMLAny(&node)
+	assert.NoError(t, err)
+	assert.Equal(t, 123, value)
+
+	// Empty document
+	value, err = DecodeYAMLAny(&yaml.Node{Kind: yaml.DocumentNode})
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	// OrderedMap.UnmarshalYAML is strict
+	assert.Error(t, yaml.Unmarshal([]byte("- foo\n"), New()))
+}
+
+// textID is encoded by the encoding.TextMarshaler.
+type textID int
+
+func (v textID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("id-%d", v)), nil
+}
+
+// jsonValue is encoded by the json.Marshaler.
+type jsonValue struct {
+	Value int
+}
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"value":%d,"kind":"json","flag":"true"}`, v.Value)), nil
+}
+
+// yamlValue is encoded by the yaml.Marshaler and the json.Marshaler.
+type yamlValue struct{}
+
+func (v yamlValue) MarshalYAML() (any, error) {
+	return "yaml", nil
+}
+
+func (v yamlValue) MarshalJSON() ([]byte, error) {
+	return []byte(`"json"`), nil
+}
+
+func TestOrderedMap_MarshalYAML_Marshalers(t *testing.T) {
+	t.Parallel()
+	id := textID(2)
+	o := New()
+	o.Set("text", textID(1))
+	o.Set("textPtr", &id)
+	o.Set("json", jsonValue{Value: 1})
+	o.Set("yaml", yamlValue{})
+	o.Set("slice", []any{textID(3), jsonValue{Value: 2}, yamlValue{}})
+	o.Set("map", *FromPairs([]Pair{{Key: "b", Value: textID(4)}, {Key: "a", Value: 1}}))
+
+	expected := `
+text: id-1
+textPtr: id-2
+json:
+  value: 1
+  kind: json
+  flag: "true"
+yaml: yaml
+slice:
+  - id-3
+  - value: 2
+    kind: json
+    flag: "true"
+  - yaml
+map:
+  b: id-4
+  a: 1
+`
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	assert.NoError(t, encoder.Encode(o))
+	assert.Equal(t, strings.TrimLeft(expected, "\n"), out.String())
+}
@@ -1,11 +1,13 @@
 package testproject
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gofrs/flock"
 )
@@ -35,12 +37,17 @@ func newFsLocker() (*fsLocker, error) {
 }
 
 // fsProjectLocker is implementation of locker using flock and mutex to perform mutual exclusion of project access on both process and goroutine level.
+// Shared read access is implemented by the shared flock and the read lock of the mutex.
 type fsProjectLocker struct {
 	fsLocker  *fsLocker
 	projectID string
-	lock      *sync.Mutex  // lock between goroutines
-	fsLock    *flock.Flock // fsLock between processes
+	lock      *sync.RWMutex // lock between goroutines
+	fsLock    *flock.Flock  // fsLock between processes
 	locked    bool
+	token     lockToken              // token is the token of the exclusive lock
+	readers   map[lockToken]struct{} // readers are tokens of the holders of the shared read access
+	lastToken lockToken              // lastToken is the last issued token
+	stateLock sync.Mutex             // stateLock protects the locked, token, readers and lastToken fields
 }
 
 func (fl *fsLocker) newForProject(p *Project) projectLocker {
@@ -51,33 +58,104 @@ func (fl *fsLocker) newForProject(p *Project) projectLocker {
 	return &fsProjectLocker{
 		fsLocker:  fl,
 		projectID: projectID,
-		lock:      &sync.Mutex{},
+		lock:      &sync.RWMutex{},
 		fsLock:    fsLock,
+		readers:   make(map[lockToken]struct{}),
 	}
 }
 
-func (fl *fsProjectLocker) tryLock() bool {
+func (fl *fsProjectLocker) tryLock() (lockToken, bool) {
+	// This lock works inside one process, between goroutines
+	if !fl.lock.TryLock() {
+		// Busy
+		return 0, false
+	}
+
 	// This FS lock works between processes
 	if locked, err := fl.fsLock.TryLock(); err != nil {
+		fl.lock.Unlock()
 		panic(fmt.Errorf(`cannot lock test project: %w`, err))
 	} else if !locked {
 		// Busy
-		return false
+		fl.lock.Unlock()
+		return 0, false
+	}
+
+	// Locked
+	fl.stateLock.Lock()
+	defer fl.stateLock.Unlock()
+	fl.locked = true
+	fl.token = fl.nextToken()
+	return fl.token, true
+}
+
+// unlock project if it is no more needed in test.
+// The token must be from the current lock acquisition, otherwise the call is no-op.
+func (fl *fsProjectLocker) unlock(token lockToken) {
+	fl.stateLock.Lock()
+	defer fl.stateLock.Unlock()
+
+	// Project has been already unlocked, for example by ProjectsPool.ReleaseAll
+	if !fl.locked || fl.token != token {
+		return
 	}
 
+	fl.releaseWriter()
+}
+
+// tryRLock locks the project for shared read access, other readers can hold the project at the same time.
+func (fl *fsProjectLocker) tryRLock() (lockToken, bool) {
 	// This lock works inside one process, between goroutines
-	if !fl.lock.TryLock() {
+	if !fl.lock.TryRLock() {
 		// Busy
-		return false
+		return 0, false
 	}
 
-	// Locked
-	fl.locked = true
-	return true
+	fl.stateLock.Lock()
+	defer fl.stateLock.Unlock()
+
+	// The shared FS lock is held once for all readers in the process
+	if len(fl.readers) == 0 {
+		if locked, err := fl.fsLock.TryRLock(); err != nil {
+			fl.lock.RUnlock()
+			panic(fmt.Errorf(`cannot lock test project: %w`, err))
+		} else if !locked {
+			// Busy
+			fl.lock.RUnlock()
+			return 0, false
+		}
+	}
+
+	token := fl.nextToken()
+	fl.readers[token] = struct{}{}
+	return token, true
 }
 
-// unlock project if it is no more needed in test.
-func (fl *fsProjectLocker) unlock() {
+// runlock releases one shared read access, identified by the token.
+func (fl *fsProjectLocker) runlock(token lockToken) {
+	fl.stateLock.Lock()
+	defer fl.stateLock.Unlock()
+	fl.releaseReader(token)
+}
+
+// release releases the exclusive lock and all shared read accesses.
+func (fl *fsProjectLocker) release() {
+	fl.stateLock.Lock()
+	defer fl.stateLock.Unlock()
+	if fl.locked {
+		fl.releaseWriter()
+	}
+	for token := range fl.readers {
+		fl.releaseReader(token)
+	}
+}
+
+func (fl *fsProjectLocker) nextToken() lockToken {
+	fl.lastToken++
+	return fl.lastToken
+}
+
+func (fl *fsProjectLocker) releaseWriter() {
 	defer fl.lock.Unlock()
 	fl.locked = false
 	if err := fl.fsLock.Unlock(); err != nil {
@@ -85,6 +163,43 @@ func (fl *fsProjectLocker) unlock() {
 	}
 }
 
+func (fl *fsProjectLocker) releaseReader(token lockToken) {
+	// Project has been already unlocked, for example by ProjectsPool.ReleaseAll
+	if _, found := fl.readers[token]; !found {
+		return
+	}
+
+	defer fl.lock.RUnlock()
+	delete(fl.readers, token)
+	if len(fl.readers) == 0 {
+		if err := fl.fsLock.Unlock(); err != nil {
+			panic(fmt.Errorf(`cannot unlock test project: %w`, err))
+		}
+	}
+}
+
 func (fl *fsProjectLocker) isLocked() bool {
-	return fl.locked
+	fl.stateLock.Lock()
+	defer fl.stateLock.Unlock()
+	return fl.locked || len(fl.readers) > 0
+}
+
+// ping always succeeds, the locks dir is created on the locker initialization.
+func (fl *fsProjectLocker) ping(_ context.Context) error {
+	return nil
+}
+
+// refresh is not supported, the file system lock never expires.
+func (fl *fsProjectLocker) refresh(_ context.Context) error {
+	return ErrLockWithoutTTL
+}
+
+// lockErr returns nil, the file system lock cannot be lost.
+func (fl *fsProjectLocker) lockErr() error {
+	return nil
+}
+
+// expiresAt returns false, the file system lock never expires.
+func (fl *fsProjectLocker) expiresAt() (time.Time, bool) {
+	return time.Time{}, false
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 	"time"
@@ -14,16 +15,28 @@ import (
 )
 
 const (
+	// TTL is the default TTL of the redis lock, it can be changed by the TEST_KBC_PROJECTS_LOCK_TTL environment variable.
 	TTL = 2 * time.Minute
+	// minTTL is the minimum TTL of the redis lock, the lock is extended when 1/4 of the TTL passed.
+	minTTL = time.Second
 )
 
 // redisLocker is factory constructing redisProjectLockers.
 type redisLocker struct {
 	redisClient *redis.Client
 	locker      *redislock.Client
+	obtain      func(ctx context.Context, key string, ttl time.Duration) (redisLock, error) // obtain is replaced in tests
+	ttl         time.Duration
+	onError     func(err error) // onError is called if a lock cannot be extended or released
 }
 
-func newRedisLocker(redisHost, redisPassword string) (*redisLocker, error) {
+// redisLock is implemented by *redislock.Lock.
+type redisLock interface {
+	Refresh(ctx context.Context, ttl time.Duration, opt *redislock.Options) error
+	Release(ctx context.Context) error
+}
+
+func newRedisLocker(redisHost, redisPassword string, ttl time.Duration) (*redisLocker, error) {
 	var client *redis.Client
 	var locker *redislock.Client
 	_, after, found := strings.Cut(redisHost, "://")
@@ -50,6 +63,13 @@ func newRedisLocker(redisHost, redisPassword string) (*redisLocker, error) {
 	return &redisLocker{
 		redisClient: client,
 		locker:      locker,
+		obtain: func(ctx context.Context, key string, ttl time.Duration) (redisLock, error) {
+			return locker.Obtain(ctx, key, ttl, nil)
+		},
+		ttl: ttl,
+		onError: func(err error) {
+			log.Printf("testproject: %s", err)
+		},
 	}, nil
 }
 
@@ -57,10 +77,13 @@ func newRedisLocker(redisHost, redisPassword string) (*redisLocker, error) {
 type redisProjectLocker struct {
 	redisLocker *redisLocker
 	projectID   string
-	redisLock   *redislock.Lock // lock between projects using redis
+	redisLock   redisLock // lock between projects using redis
 	cancel      func()
 	locked      bool
-	mu          sync.Mutex
+	token       lockToken  // token of the current lock acquisition
+	expires     time.Time  // expires is expiration time of the redis lock, it is updated on each extension
+	err         error      // err is set if the lock expired, because it could not be extended
+	mu          sync.Mutex // mu protects all fields above, the lock state is read concurrently, for example by ProjectsPool.Stats
 }
 
 func (rl *redisLocker) newForProject(p *Project) projectLocker {
@@ -70,26 +93,34 @@ func (rl *redisLocker) newForProject(p *Project) projectLocker {
 	}
 }
 
-func (rl *redisProjectLocker) tryLock() bool {
-	lock, err := rl.redisLocker.locker.Obtain(context.Background(), rl.projectID, TTL, nil)
+func (rl *redisProjectLocker) tryLock() (lockToken, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	lock, err := rl.redisLocker.obtain(context.Background(), rl.projectID, rl.redisLocker.ttl)
 	if errors.Is(err, redislock.ErrNotObtained) {
-		return false
+		return 0, false
 	} else if err != nil {
 		panic(fmt.Errorf(`cannot lock test project using redis lock: %w`, err))
 	}
 
 	rl.redisLock = lock
+	rl.expires = time.Now().Add(rl.redisLocker.ttl)
+	rl.err = nil
 	ctxWithCancel, cancel := context.WithCancel(context.Background())
 	rl.cancel = cancel
 	go rl.extendLock(ctxWithCancel)
 	rl.locked = true
-	return true
+	rl.token++
+	return rl.token, true
 }
 
-// extendLock extends the lock forewer when TTL/4 passed.
+// extendLock extends the lock forewer when 1/4 of the TTL passed.
+// If the lock cannot be extended, the error is reported by the onError callback and the extension is retried until the lock expires.
+// The expired lock is reported by the onError callback and by the lockErr method, and the extension stops.
 // replace implementation with https://github.com/bsm/redislock/pull/73 in future.
 func (rl *redisProjectLocker) extendLock(ctx context.Context) {
-	ticker := time.NewTicker(TTL / 4)
+	ticker := time.NewTicker(rl.redisLocker.ttl / 4)
 	defer ticker.Stop()
 	for {
 		select {
@@ -98,13 +129,36 @@ func (rl *redisProjectLocker) extendLock(ctx context.Context) {
 
 		case <-ticker.C:
 			err := rl.refreshLock(ctx)
-			if err != nil {
-				panic(err)
+			if err == nil {
+				continue
+			}
+			if expiredErr := rl.checkExpired(err); expiredErr != nil {
+				rl.redisLocker.onError(expiredErr)
+				return
 			}
+			rl.redisLocker.onError(err)
 		}
 	}
 }
 
+// checkExpired returns an error and marks the lock as lost, if the lock expired.
+func (rl *redisProjectLocker) checkExpired(err error) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if time.Now().Before(rl.expires) {
+		return nil
+	}
+	rl.err = fmt.Errorf(`redis lock of test project "%s" expired: %w`, rl.projectID, err)
+	return rl.err
+}
+
+// lockErr returns an error if the lock expired, because it could not be extended.
+func (rl *redisProjectLocker) lockErr() error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.err
+}
+
 func (rl *redisProjectLocker) refreshLock(ctx context.Context) error {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -114,24 +168,78 @@ func (rl *redisProjectLocker) refreshLock(ctx context.Context) error {
 	default:
 	}
 
-	err := rl.redisLock.Refresh(ctx, TTL, nil)
+	return rl.doRefresh(ctx)
+}
+
+// refresh extends the lock immediately, see Project.RefreshLock.
+func (rl *redisProjectLocker) refresh(ctx context.Context) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.doRefresh(ctx)
+}
+
+func (rl *redisProjectLocker) doRefresh(ctx context.Context) error {
+	ttl := rl.redisLocker.ttl
+	err := rl.redisLock.Refresh(ctx, ttl, nil)
 	if err != nil {
 		return fmt.Errorf(`cannot extend the redis lock: %w`, err)
 	}
 
+	rl.expires = time.Now().Add(ttl)
 	return nil
 }
 
-func (rl *redisProjectLocker) unlock() {
+func (rl *redisProjectLocker) expiresAt() (time.Time, bool) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	return rl.expires, true
+}
+
+// unlock releases the lock, if the token is from the current lock acquisition.
+func (rl *redisProjectLocker) unlock(token lockToken) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// Project has been already unlocked, for example by ProjectsPool.ReleaseAll
+	if !rl.locked || rl.token != token {
+		return
+	}
+
+	rl.doUnlock()
+}
+
+func (rl *redisProjectLocker) doUnlock() {
 	rl.cancel()
 	rl.locked = false
 	if err := rl.redisLock.Release(context.Background()); err != nil {
-		panic(fmt.Errorf(`cannot unlock test project using redis lock: %w`, err))
+		rl.redisLocker.onError(fmt.Errorf(`cannot unlock test project using redis lock: %w`, err))
+	}
+}
+
+// tryRLock falls back to the exclusive lock, the redis lock doesn't support shared access.
+func (rl *redisProjectLocker) tryRLock() (lockToken, bool) {
+	return rl.tryLock()
+}
+
+func (rl *redisProjectLocker) runlock(token lockToken) {
+	rl.unlock(token)
+}
+
+// release releases the current lock acquisition, regardless of the token.
+func (rl *redisProjectLocker) release() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.locked {
+		rl.doUnlock()
 	}
 }
 
 func (rl *redisProjectLocker) isLocked() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 	return rl.locked
 }
+
+func (rl *redisProjectLocker) ping(ctx context.Context) error {
+	return rl.redisLocker.redisClient.Ping(ctx).Err()
+}
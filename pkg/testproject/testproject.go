@@ -18,13 +18,18 @@
 package testproject
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/locales/en"
@@ -40,31 +45,75 @@ const (
 
 	BackendSnowflake               = "snowflake"
 	BackendBigQuery                = "bigquery"
+	TestKbcProjectsKey             = "TEST_KBC_PROJECTS"
 	TestKbcProjectsFileKey         = "TEST_KBC_PROJECTS_FILE"
 	TestKbcProjectsLockDirNameKey  = "TEST_KBC_PROJECTS_LOCK_DIR_NAME"
 	TestKbcProjectsLockHostKey     = "TEST_KBC_PROJECTS_LOCK_HOST"
 	TestKbcProjectsLockPasswordKey = "TEST_KBC_PROJECTS_LOCK_PASSWORD"
 	TestKbcProjectsLockTLSKey      = "TEST_KBC_PROJECTS_LOCK_TLS"
+	TestKbcProjectsLockTTLKey      = "TEST_KBC_PROJECTS_LOCK_TTL"
 )
 
 const QueueV1 = "v1"
 
-var pool *ProjectsPool       // nolint gochecknoglobals
-var poolLock = &sync.Mutex{} // nolint gochecknoglobals
+// Selection is strategy of choosing a project from the ProjectsPool, see WithSelection.
+type Selection int
+
+const (
+	// InOrder scans projects in the order of definitions, it is the default strategy.
+	InOrder Selection = iota
+	// RoundRobin starts the scan from the next project on each call, so the load is spread across projects.
+	RoundRobin
+	// Random starts the scan from a random project.
+	Random
+)
+
+// ErrLockWithoutTTL is returned by Project.RefreshLock if the lock never expires, for example the file system lock.
+var ErrLockWithoutTTL = errors.New("test project lock has no TTL")
+
+var pool *ProjectsPool              // nolint gochecknoglobals
+var poolLock = &sync.Mutex{}        // nolint gochecknoglobals
+var roundRobinCounter atomic.Uint64 // nolint gochecknoglobals
 
 type locker interface {
 	newForProject(p *Project) projectLocker
 }
 
+// lockToken identifies one acquisition of a project lock.
+// The token is checked on unlock, so an UnlockFn of a released acquisition, for example by ProjectsPool.ReleaseAll, is a no-op.
+type lockToken uint64
+
 type projectLocker interface {
-	tryLock() bool
-	unlock()
+	tryLock() (lockToken, bool)
+	tryRLock() (lockToken, bool)
+	unlock(token lockToken)
+	runlock(token lockToken)
+	release()
 	isLocked() bool
+	ping(ctx context.Context) error
+	refresh(ctx context.Context) error
+	expiresAt() (time.Time, bool)
+	lockErr() error
 }
 
 // ProjectsPool a group of testing projects.
 type ProjectsPool []*Project
 
+// PoolStats contains statistics of the ProjectsPool, see ProjectsPool.Stats method.
+type PoolStats struct {
+	PoolCounts
+	ByBackend        map[string]PoolCounts
+	ByStagingStorage map[string]PoolCounts
+}
+
+// PoolCounts contains number of projects in the ProjectsPool.
+// Only locks acquired by the current process are counted as locked.
+type PoolCounts struct {
+	Total     int
+	Locked    int
+	Available int
+}
+
 // Project represents a testing project for E2E tests.
 type Project struct {
 	definition Definition
@@ -73,14 +122,15 @@ type Project struct {
 
 // Definition is project Definition parsed from the ENV.
 type Definition struct {
-	Host                 string `json:"host" validate:"required"`
-	Token                string `json:"token" validate:"required"`
-	StagingStorage       string `json:"stagingStorage" validate:"required"`
-	Backend              string `json:"backend" validate:"required"`
-	ProjectID            int    `json:"project" validate:"required"`
-	LegacyTransformation bool   `json:"legacyTransformation"`
-	Queue                string `json:"queue,omitempty"`
-	IsGuest              bool   `json:"isGuest,omitempty"`
+	Host                 string            `json:"host" validate:"required"`
+	Token                string            `json:"token" validate:"required"`
+	StagingStorage       string            `json:"stagingStorage" validate:"required"`
+	Backend              string            `json:"backend" validate:"required"`
+	ProjectID            int               `json:"project" validate:"required"`
+	LegacyTransformation bool              `json:"legacyTransformation"`
+	Queue                string            `json:"queue,omitempty"`
+	IsGuest              bool              `json:"isGuest,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
 }
 
 // UnlockFn must be called if the project is no longer used.
@@ -91,11 +141,32 @@ type Option func(c *config)
 
 // config for the GetTestProjectForTest and GetTestProject functions.
 type config struct {
-	backend              string
+	backends             []string
 	stagingStorage       string
 	legacyTransformation bool
 	queueV1              bool
 	isGuest              bool
+	projectID            int
+	labels               map[string]string
+	sharedReadAccess     bool
+	timeout              time.Duration
+	selection            Selection
+}
+
+// PoolOption for the GetProjectsFrom and GetProjectsFromDir functions.
+type PoolOption func(c *poolConfig)
+
+// poolConfig for the GetProjectsFrom and GetProjectsFromDir functions.
+type poolConfig struct {
+	onLockError func(err error)
+}
+
+// WithLockErrorHandler sets the callback called if a redis lock cannot be extended or released.
+// By default, the error is logged. The holder of the project can check the lock by Project.LockErr.
+func WithLockErrorHandler(fn func(err error)) PoolOption {
+	return func(c *poolConfig) {
+		c.onLockError = fn
+	}
 }
 
 // TInterface is cleanup part of the *testing.T.
@@ -135,13 +206,20 @@ func WithQueueV1() Option {
 
 func WithSnowflakeBackend() Option {
 	return func(c *config) {
-		c.backend = BackendSnowflake
+		c.backends = []string{BackendSnowflake}
 	}
 }
 
 func WithBigQueryBackend() Option {
 	return func(c *config) {
-		c.backend = BackendBigQuery
+		c.backends = []string{BackendBigQuery}
+	}
+}
+
+// WithBackends requires a project with one of the backends, an empty list means any backend.
+func WithBackends(backends ...string) Option {
+	return func(c *config) {
+		c.backends = backends
 	}
 }
 
@@ -157,18 +235,87 @@ func WithIsGuest() Option {
 	}
 }
 
+// WithProjectID requires the project with the ID.
+func WithProjectID(id int) Option {
+	return func(c *config) {
+		c.projectID = id
+	}
+}
+
+// WithLabel requires the project with the label, see Definition.Labels.
+// The option can be used multiple times, all labels must match.
+func WithLabel(key, value string) Option {
+	return func(c *config) {
+		if c.labels == nil {
+			c.labels = make(map[string]string)
+		}
+		c.labels[key] = value
+	}
+}
+
+// WithSharedReadAccess locks the project for shared read access, for tests which only read the project data.
+// Multiple tests with the shared read access can hold the same project at the same time,
+// a test without the option waits until all readers release the project.
+//
+// Only the file system locker supports the shared access,
+// the redis locker, see TEST_KBC_PROJECTS_LOCK_HOST, locks the project exclusively.
+func WithSharedReadAccess() Option {
+	return func(c *config) {
+		c.sharedReadAccess = true
+	}
+}
+
+// WithTimeout limits waiting for a free project, an error is returned if no project is released within the timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+// WithSelection sets the strategy of choosing a project, see Selection.
+// The strategy changes only the project from which the scan of compatible projects starts,
+// so a free project is always found if there is one.
+// The RoundRobin counter is shared by all pools in the process.
+func WithSelection(selection Selection) Option {
+	return func(c *config) {
+		c.selection = selection
+	}
+}
+
 func (c *config) IsCompatible(p *Project) bool {
 	matchStagingStorage := len(c.stagingStorage) == 0 || p.definition.StagingStorage == c.stagingStorage
 
 	matchQueue := (p.definition.Queue == QueueV1) == c.queueV1 // QueueV2 is required, if QueueV1 is not explicitly requested
 
-	matchBackend := len(c.backend) == 0 || p.definition.Backend == c.backend
+	matchBackend := len(c.backends) == 0 || slices.Contains(c.backends, p.definition.Backend)
 
 	matchLegacyTransformation := !c.legacyTransformation || p.definition.LegacyTransformation == c.legacyTransformation
 
 	matchIsGuest := p.definition.IsGuest == c.isGuest
 
-	return matchStagingStorage && matchQueue && matchBackend && matchLegacyTransformation && matchIsGuest
+	matchProjectID := c.projectID == 0 || p.definition.ProjectID == c.projectID
+
+	matchLabels := true
+	for key, value := range c.labels {
+		if v, found := p.definition.Labels[key]; !found || v != value {
+			matchLabels = false
+			break
+		}
+	}
+
+	return matchStagingStorage && matchQueue && matchBackend && matchLegacyTransformation && matchIsGuest && matchProjectID && matchLabels
+}
+
+// startIndex returns index of the project from which the scan starts, see Selection.
+func (c *config) startIndex(count int) int {
+	switch c.selection {
+	case RoundRobin:
+		return int((roundRobinCounter.Add(1) - 1) % uint64(count))
+	case Random:
+		return rand.IntN(count) // nolint: gosec
+	default:
+		return 0
+	}
 }
 
 func (c *config) String() string {
@@ -181,8 +328,8 @@ func (c *config) String() string {
 		out = append(out, "queue v1")
 	}
 
-	if len(c.backend) > 0 {
-		out = append(out, fmt.Sprintf("backend %s", c.backend))
+	if len(c.backends) > 0 {
+		out = append(out, fmt.Sprintf("backend %s", strings.Join(c.backends, " or ")))
 	}
 
 	if c.legacyTransformation {
@@ -193,6 +340,17 @@ func (c *config) String() string {
 		out = append(out, "guest project")
 	}
 
+	if c.projectID != 0 {
+		out = append(out, fmt.Sprintf("project %d", c.projectID))
+	}
+
+	labels := make([]string, 0, len(c.labels))
+	for key, value := range c.labels {
+		labels = append(labels, fmt.Sprintf("label %s=%s", key, value))
+	}
+	slices.Sort(labels)
+	out = append(out, labels...)
+
 	return "(" + strings.Join(out, ", ") + ")"
 }
 
@@ -210,6 +368,13 @@ func GetTestProject(opts ...Option) (*Project, UnlockFn, error) {
 	return mustGetProjects().GetTestProject(opts...)
 }
 
+// GetTestProjectCtx locks and returns a testing project specified in TEST_KBC_PROJECTS environment variable.
+// The returned UnlockFn function must be called to free project, when the project is no longer used (e.g. defer unlockFn())
+// If no project is available, the function waits until a project is released or the context is cancelled.
+func GetTestProjectCtx(ctx context.Context, opts ...Option) (*Project, UnlockFn, error) {
+	return mustGetProjects().GetTestProjectCtx(ctx, opts...)
+}
+
 func GetTestProjectInPath(path string, opts ...Option) (*Project, UnlockFn, error) {
 	return mustGetProjectsInPath(path).GetTestProject(opts...)
 }
@@ -236,6 +401,13 @@ func (v ProjectsPool) GetTestProjectForTest(t TInterface, opts ...Option) (*Proj
 // The returned UnlockFn function must be called to free project, when the project is no longer used (e.g. defer unlockFn())
 // If no project is available, the function waits until a project is released.
 func (v ProjectsPool) GetTestProject(opts ...Option) (*Project, UnlockFn, error) {
+	return v.GetTestProjectCtx(context.Background(), opts...)
+}
+
+// GetTestProjectCtx locks and returns a testing project specified in TEST_KBC_PROJECTS environment variable.
+// The returned UnlockFn function must be called to free project, when the project is no longer used (e.g. defer unlockFn())
+// If no project is available, the function waits until a project is released or the context is cancelled.
+func (v ProjectsPool) GetTestProjectCtx(ctx context.Context, opts ...Option) (*Project, UnlockFn, error) {
 	c := &config{}
 	for _, opt := range opts {
 		opt(c)
@@ -245,15 +417,21 @@ func (v ProjectsPool) GetTestProject(opts ...Option) (*Project, UnlockFn, error)
 		return nil, nil, fmt.Errorf(`no test project`)
 	}
 
+	parentCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := c.startIndex(len(v))
 	for {
 		// Try to find a free project
 		anyProjectFound := false
-		for _, p := range v {
+		for i := range v {
+			p := v[(start+i)%len(v)]
 			if c.IsCompatible(p) {
-				if p.locker.tryLock() {
-					unlockFn := func() {
-						p.locker.unlock()
-					}
+				if unlockFn, ok := p.tryLock(c.sharedReadAccess); ok {
 					return p, unlockFn, nil
 				}
 
@@ -266,10 +444,87 @@ func (v ProjectsPool) GetTestProject(opts ...Option) (*Project, UnlockFn, error)
 		}
 
 		// No free project -> wait
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			if c.timeout > 0 && parentCtx.Err() == nil {
+				return nil, nil, fmt.Errorf(`no test project became available within %s`, c.timeout)
+			}
+			return nil, nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
 }
 
+// Stats returns number of total, locked and available projects, also grouped by backend and staging storage.
+func (v ProjectsPool) Stats() PoolStats {
+	stats := PoolStats{
+		ByBackend:        make(map[string]PoolCounts),
+		ByStagingStorage: make(map[string]PoolCounts),
+	}
+	for _, p := range v {
+		locked := p.locker.isLocked()
+		stats.PoolCounts = stats.PoolCounts.add(locked)
+		stats.ByBackend[p.definition.Backend] = stats.ByBackend[p.definition.Backend].add(locked)
+		stats.ByStagingStorage[p.definition.StagingStorage] = stats.ByStagingStorage[p.definition.StagingStorage].add(locked)
+	}
+	return stats
+}
+
+func (c PoolCounts) add(locked bool) PoolCounts {
+	c.Total++
+	if locked {
+		c.Locked++
+	} else {
+		c.Available++
+	}
+	return c
+}
+
+// ReleaseAll unlocks all projects locked by the current process, including the shared read access, it is intended for a cleanup in the TestMain.
+// Projects that are not locked are skipped, so it is safe to call the method repeatedly.
+// UnlockFn functions of the released locks become no-op, so they don't unlock the project locked again later.
+func (v ProjectsPool) ReleaseAll() {
+	for _, p := range v {
+		p.locker.release()
+	}
+}
+
+// Definitions returns copies of all project definitions, no project is locked.
+func (v ProjectsPool) Definitions() []Definition {
+	out := make([]Definition, 0, len(v))
+	for _, p := range v {
+		def := p.definition
+		def.Labels = maps.Clone(def.Labels)
+		out = append(out, def)
+	}
+	return out
+}
+
+// HealthCheck checks that the locker is reachable and all project definitions are valid.
+// All found problems are returned.
+func (v ProjectsPool) HealthCheck(ctx context.Context) error {
+	validate, err := newValidator()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	// All projects of the pool share the locker, so it is checked only once
+	if len(v) > 0 {
+		if err := v[0].locker.ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf(`locker is not available: %w`, err))
+		}
+	}
+
+	for _, p := range v {
+		if err := validate.Struct(p.definition); err != nil {
+			errs = append(errs, fmt.Errorf(`project "%d" is invalid: %w`, p.definition.ProjectID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ID returns id of the project.
 func (p *Project) ID() int {
 	p.assertLocked()
@@ -312,21 +567,118 @@ func (p *Project) IsGuest() bool {
 	return p.definition.IsGuest
 }
 
+// LockErr returns an error if the project lock has been lost, for example if the redis lock expired, because it could not be extended.
+// The project should not be used after the lock is lost, another test can lock it.
+func (p *Project) LockErr() error {
+	p.assertLocked()
+	return p.locker.lockErr()
+}
+
+// RefreshLock extends the lease of the project lock immediately, without waiting for the periodic extension.
+// ErrLockWithoutTTL is returned if the lock never expires.
+func (p *Project) RefreshLock(ctx context.Context) error {
+	p.assertLocked()
+	return p.locker.refresh(ctx)
+}
+
+// LockExpiresAt returns expiration time of the project lock.
+// False is returned if the lock never expires.
+func (p *Project) LockExpiresAt() (time.Time, bool) {
+	p.assertLocked()
+	return p.locker.expiresAt()
+}
+
+// tryLock locks the project exclusively or for the shared read access.
+func (p *Project) tryLock(shared bool) (UnlockFn, bool) {
+	if shared {
+		if token, ok := p.locker.tryRLock(); ok {
+			return func() { p.locker.runlock(token) }, true
+		}
+		return nil, false
+	}
+	if token, ok := p.locker.tryLock(); ok {
+		return func() { p.locker.unlock(token) }, true
+	}
+	return nil, false
+}
+
 func (p *Project) assertLocked() {
 	if !p.locker.isLocked() {
 		panic(fmt.Errorf(`test project "%d" is not locked`, p.definition.ProjectID))
 	}
 }
 
-func MustGetProjectsFrom(str string) ProjectsPool {
-	projects, err := GetProjectsFrom(str)
+func MustGetProjectsFrom(str string, opts ...PoolOption) ProjectsPool {
+	projects, err := GetProjectsFrom(str, opts...)
 	if err != nil {
 		panic(err)
 	}
 	return projects
 }
 
-func GetProjectsFrom(str string) (ProjectsPool, error) {
+func GetProjectsFrom(str string, opts ...PoolOption) (ProjectsPool, error) {
+	defs, err := decodeDefinitions(str)
+	if err != nil {
+		return nil, err
+	}
+	return newProjectsPool(defs, opts)
+}
+
+// GetProjectsFromFiles loads projects from one or more JSON files.
+// Project IDs must be unique across all files.
+func GetProjectsFromFiles(paths []string, opts ...PoolOption) (ProjectsPool, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf(`please specify one or more files with Keboola Connection testing projects`)
+	}
+
+	defs := make([]Definition, 0)
+	defPaths := make(map[int]string)
+	for _, path := range paths {
+		content, err := os.ReadFile(path) // nolint: forbidigo
+		if err != nil {
+			return nil, fmt.Errorf(`cannot read projects file "%s": %w`, path, err)
+		}
+
+		fileDefs, err := decodeDefinitions(string(content))
+		if err != nil {
+			return nil, fmt.Errorf(`cannot load projects file "%s": %w`, path, err)
+		}
+
+		for _, d := range fileDefs {
+			if otherPath, found := defPaths[d.ProjectID]; found {
+				return nil, fmt.Errorf(`duplicate project "%d" found in "%s" and "%s"`, d.ProjectID, otherPath, path)
+			}
+			defPaths[d.ProjectID] = path
+			defs = append(defs, d)
+		}
+	}
+
+	return newProjectsPool(defs, opts)
+}
+
+// GetProjectsFromDir loads projects from all JSON files in the directory, see GetProjectsFromFiles.
+func GetProjectsFromDir(dir string, opts ...PoolOption) (ProjectsPool, error) {
+	entries, err := os.ReadDir(dir) // nolint: forbidigo
+	if err != nil {
+		return nil, fmt.Errorf(`cannot read projects dir "%s": %w`, dir, err)
+	}
+
+	paths := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf(`no JSON file found in projects dir "%s"`, dir)
+	}
+
+	return GetProjectsFromFiles(paths, opts...)
+}
+
+// decodeDefinitions decodes non-empty list of project definitions from JSON.
+func decodeDefinitions(str string) ([]Definition, error) {
 	// No test project
 	if str == "" {
 		return nil, fmt.Errorf(`please specify one or more Keboola Connection testing projects in format '[{"host":"","token":"","project":"","stagingStorage":""}]'`)
@@ -343,14 +695,22 @@ func GetProjectsFrom(str string) (ProjectsPool, error) {
 		return nil, fmt.Errorf(`please specify one or more Keboola Connection testing projects in format '[{"host":"","token":"","project":"","stagingStorage":""}]'`)
 	}
 
-	// Setup validator
-	validate := validator.New()
-	translator := ut.New(en.New()).GetFallback()
-	if err := enTranslation.RegisterDefaultTranslations(validate, translator); err != nil {
+	return defs, nil
+}
+
+// newProjectsPool validates project definitions and creates projects.
+func newProjectsPool(defs []Definition, opts []PoolOption) (ProjectsPool, error) {
+	c := &poolConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	validate, err := newValidator()
+	if err != nil {
 		return nil, err
 	}
 
-	locker, err := newLocker()
+	locker, err := newLocker(c)
 	if err != nil {
 		return nil, err
 	}
@@ -384,7 +744,16 @@ func mustGetProjectsInPath(path string) *ProjectsPool {
 	return projects
 }
 
+// ResetPool clears the process-global pool, so the next call loads the projects again.
+// It is intended for tests.
+func ResetPool() {
+	poolLock.Lock()
+	defer poolLock.Unlock()
+	pool = nil
+}
+
 // getProjects loads projects from provided file by path or environment variable TEST_KBC_PROJECTS_FILE.
+// If no file is specified, projects are loaded from the JSON in the environment variable TEST_KBC_PROJECTS.
 func getProjects(path string) (*ProjectsPool, error) {
 	poolLock.Lock()
 	defer poolLock.Unlock()
@@ -397,22 +766,29 @@ func getProjects(path string) (*ProjectsPool, error) {
 	projectsFile := path
 	if projectsFile == "" {
 		projectsFile = os.Getenv(TestKbcProjectsFileKey) // nolint: forbidigo
-		if projectsFile == "" {
-			return nil, fmt.Errorf("please set TEST_KBC_PROJECTS_FILE environment variable")
-		}
 	}
 
-	if !filepath.IsAbs(projectsFile) {
-		return nil, fmt.Errorf("the path to projects.json file should be absolute, not relative, got %s", projectsFile)
-	}
+	var projects string
+	if projectsFile == "" {
+		// Init projects from the inline json
+		projects = os.Getenv(TestKbcProjectsKey) // nolint: forbidigo
+		if projects == "" {
+			return nil, fmt.Errorf("please set TEST_KBC_PROJECTS_FILE or TEST_KBC_PROJECTS environment variable")
+		}
+	} else {
+		if !filepath.IsAbs(projectsFile) {
+			return nil, fmt.Errorf("the path to projects.json file should be absolute, not relative, got %s", projectsFile)
+		}
 
-	// Init projects from the json projects file
-	projects, err := os.ReadFile(projectsFile) // nolint: forbidigo
-	if err != nil {
-		return nil, fmt.Errorf("error occurred during project pool setup: %w", err)
+		// Init projects from the json projects file
+		content, err := os.ReadFile(projectsFile) // nolint: forbidigo
+		if err != nil {
+			return nil, fmt.Errorf("error occurred during project pool setup: %w", err)
+		}
+		projects = string(content)
 	}
 
-	if v, err := GetProjectsFrom(string(projects)); err == nil {
+	if v, err := GetProjectsFrom(projects); err == nil {
 		pool = &v // initialization run only once
 		return pool, nil
 	} else {
@@ -420,7 +796,7 @@ func getProjects(path string) (*ProjectsPool, error) {
 	}
 }
 
-func newLocker() (locker, error) {
+func newLocker(c *poolConfig) (locker, error) {
 	redisHost := os.Getenv(TestKbcProjectsLockHostKey)         // nolint: forbidigo
 	redisPassword := os.Getenv(TestKbcProjectsLockPasswordKey) // nolint: forbidigo
 	if redisHost == "" && redisPassword == "" {
@@ -430,14 +806,52 @@ func newLocker() (locker, error) {
 		return nil, errors.New("redis password is required")
 	}
 
-	locker, err := newRedisLocker(redisHost, redisPassword)
+	ttl, err := lockTTL()
+	if err != nil {
+		return nil, err
+	}
+
+	locker, err := newRedisLocker(redisHost, redisPassword, ttl)
 	if err != nil {
 		return nil, err
 	}
+	if c.onLockError != nil {
+		locker.onError = c.onLockError
+	}
 
 	return locker, nil
 }
 
+// newValidator creates validator of the project Definition.
+func newValidator() (*validator.Validate, error) {
+	validate := validator.New()
+	translator := ut.New(en.New()).GetFallback()
+	if err := enTranslation.RegisterDefaultTranslations(validate, translator); err != nil {
+		return nil, err
+	}
+	return validate, nil
+}
+
+// lockTTL returns TTL of the redis lock from the TEST_KBC_PROJECTS_LOCK_TTL environment variable or the default TTL.
+func lockTTL() (time.Duration, error) {
+	value := os.Getenv(TestKbcProjectsLockTTLKey) // nolint: forbidigo
+	if value == "" {
+		return TTL, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid %s value "%s": %w`, TestKbcProjectsLockTTLKey, value, err)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf(`invalid %s value "%s": must be positive`, TestKbcProjectsLockTTLKey, value)
+	}
+	if ttl < minTTL {
+		return 0, fmt.Errorf(`invalid %s value "%s": must be at least %s`, TestKbcProjectsLockTTLKey, value, minTTL)
+	}
+	return ttl, nil
+}
+
 // initProject - init test project handler and lock it.
 func newProject(l locker, def Definition, validate *validator.Validate) (*Project, error) {
 	if err := validate.Struct(def); err != nil {
@@ -1,11 +1,19 @@
 package testproject
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/bsm/redislock"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -263,3 +271,772 @@ func projectsForTest() string {
 	}
 	return string(j)
 }
+
+func TestGetTestProjectCtx_Timeout(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[{"project": 1001,"backend":"snowflake", "host": "ctx.keboola.com", "token": "bar", "stagingStorage": "s3"}]`)
+	require.NoError(t, err)
+
+	// Lock the only project
+	project1, unlockFn1, err := projects.GetTestProject()
+	require.NoError(t, err)
+	defer unlockFn1()
+	assert.Equal(t, 1001, project1.ID())
+
+	// No project is available
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	project2, unlockFn2, err := projects.GetTestProjectCtx(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, project2)
+	assert.Nil(t, unlockFn2)
+}
+
+func TestGetTestProject_WithTimeout(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[{"project": 1002,"backend":"snowflake", "host": "timeout.keboola.com", "token": "bar", "stagingStorage": "s3"}]`)
+	require.NoError(t, err)
+
+	// Lock the only project
+	_, unlockFn1, err := projects.GetTestProject()
+	require.NoError(t, err)
+	defer unlockFn1()
+
+	// No project is available
+	project2, unlockFn2, err := projects.GetTestProject(WithTimeout(300 * time.Millisecond))
+	assert.EqualError(t, err, `no test project became available within 300ms`)
+	assert.Nil(t, project2)
+	assert.Nil(t, unlockFn2)
+}
+
+func TestProjectsPool_Stats(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[
+  {"project": 1003,"backend":"snowflake", "host": "stats.keboola.com", "token": "bar", "stagingStorage": "s3"},
+  {"project": 1004,"backend":"bigquery", "host": "stats.keboola.com", "token": "bar", "stagingStorage": "gcs"},
+  {"project": 1005,"backend":"snowflake", "host": "stats.keboola.com", "token": "bar", "stagingStorage": "abs"}
+]`)
+	require.NoError(t, err)
+
+	// All projects are available
+	assert.Equal(t, PoolStats{
+		PoolCounts: PoolCounts{Total: 3, Locked: 0, Available: 3},
+		ByBackend: map[string]PoolCounts{
+			BackendSnowflake: {Total: 2, Locked: 0, Available: 2},
+			BackendBigQuery:  {Total: 1, Locked: 0, Available: 1},
+		},
+		ByStagingStorage: map[string]PoolCounts{
+			StagingStorageS3:  {Total: 1, Locked: 0, Available: 1},
+			StagingStorageGCS: {Total: 1, Locked: 0, Available: 1},
+			StagingStorageABS: {Total: 1, Locked: 0, Available: 1},
+		},
+	}, projects.Stats())
+
+	// Lock one project
+	_, unlockFn, err := projects.GetTestProject(WithBigQueryBackend())
+	require.NoError(t, err)
+	stats := projects.Stats()
+	assert.Equal(t, PoolCounts{Total: 3, Locked: 1, Available: 2}, stats.PoolCounts)
+	assert.Equal(t, PoolCounts{Total: 1, Locked: 1, Available: 0}, stats.ByBackend[BackendBigQuery])
+	assert.Equal(t, PoolCounts{Total: 1, Locked: 1, Available: 0}, stats.ByStagingStorage[StagingStorageGCS])
+
+	// Unlock
+	unlockFn()
+	assert.Equal(t, PoolCounts{Total: 3, Locked: 0, Available: 3}, projects.Stats().PoolCounts)
+}
+
+func TestGetTestProject_WithBackends(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[
+  {"project": 1006,"backend":"redshift", "host": "backends.keboola.com", "token": "bar", "stagingStorage": "s3"},
+  {"project": 1007,"backend":"bigquery", "host": "backends.keboola.com", "token": "bar", "stagingStorage": "gcs"},
+  {"project": 1008,"backend":"snowflake", "host": "backends.keboola.com", "token": "bar", "stagingStorage": "abs"}
+]`)
+	require.NoError(t, err)
+
+	// Match one of the backends
+	project1, unlockFn1, err := projects.GetTestProject(WithBackends(BackendSnowflake, BackendBigQuery))
+	require.NoError(t, err)
+	defer unlockFn1()
+	assert.Equal(t, 1007, project1.ID())
+
+	project2, unlockFn2, err := projects.GetTestProject(WithBackends(BackendSnowflake, BackendBigQuery))
+	require.NoError(t, err)
+	defer unlockFn2()
+	assert.Equal(t, 1008, project2.ID())
+
+	// Empty list means any backend
+	project3, unlockFn3, err := projects.GetTestProject(WithBackends())
+	require.NoError(t, err)
+	defer unlockFn3()
+	assert.Equal(t, 1006, project3.ID())
+
+	// No compatible project
+	_, _, err = projects.GetTestProject(WithBackends("synapse", "exasol"))
+	assert.EqualError(t, err, `no compatible test project found (backend synapse or exasol)`)
+}
+
+func TestGetTestProject_WithProjectID(t *testing.T) {
+	t.Parallel()
+	project1, unlockFn1, err := MustGetProjectsFrom(projectsForTest()).GetTestProject(WithProjectID(3456))
+	require.NoError(t, err)
+	defer unlockFn1()
+	assert.Equal(t, 3456, project1.ID())
+}
+
+func TestGetTestProject_NoProjectWithProjectID(t *testing.T) {
+	t.Parallel()
+	_, _, err := MustGetProjectsFrom(projectsForTest()).GetTestProject(WithProjectID(9999))
+	assert.EqualError(t, err, `no compatible test project found (project 9999)`)
+}
+
+// fakeRedisLock implements redisLock for tests.
+type fakeRedisLock struct {
+	refreshErr   error
+	releaseErr   error
+	refreshedTTL time.Duration
+}
+
+func (l *fakeRedisLock) Refresh(_ context.Context, ttl time.Duration, _ *redislock.Options) error {
+	l.refreshedTTL = ttl
+	return l.refreshErr
+}
+
+func (l *fakeRedisLock) Release(_ context.Context) error {
+	return l.releaseErr
+}
+
+// fakeRedisServer obtains redis locks in memory.
+type fakeRedisServer struct {
+	lock sync.Mutex
+	keys map[string]bool
+}
+
+// fakeServerLock is a lock obtained from the fakeRedisServer.
+type fakeServerLock struct {
+	server *fakeRedisServer
+	key    string
+}
+
+func (s *fakeRedisServer) obtain(_ context.Context, key string, _ time.Duration) (redisLock, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.keys[key] {
+		return nil, redislock.ErrNotObtained
+	}
+	s.keys[key] = true
+	return &fakeServerLock{server: s, key: key}, nil
+}
+
+func (l *fakeServerLock) Refresh(_ context.Context, _ time.Duration, _ *redislock.Options) error {
+	return nil
+}
+
+func (l *fakeServerLock) Release(_ context.Context) error {
+	l.server.lock.Lock()
+	defer l.server.lock.Unlock()
+	delete(l.server.keys, l.key)
+	return nil
+}
+
+// newFakeRedisPool creates pool of projects locked by the redis locker with the fakeRedisServer.
+func newFakeRedisPool(ttl time.Duration, projectIDs ...int) ProjectsPool {
+	server := &fakeRedisServer{keys: make(map[string]bool)}
+	locker := &redisLocker{obtain: server.obtain, ttl: ttl, onError: func(err error) {}}
+	projects := make(ProjectsPool, 0)
+	for _, id := range projectIDs {
+		p := &Project{definition: Definition{Host: "fake-redis.keboola.com", Token: "bar", Backend: BackendSnowflake, StagingStorage: StagingStorageS3, ProjectID: id}}
+		p.locker = locker.newForProject(p)
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+func TestProjectsPool_Stats_RedisLocker(t *testing.T) {
+	t.Parallel()
+	projects := newFakeRedisPool(time.Minute, 1028, 1029)
+
+	// Stats are read during acquisition and release of the projects, run with -race
+	done := make(chan struct{})
+	statsDone := make(chan struct{})
+	go func() {
+		defer close(statsDone)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				projects.Stats()
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if _, unlockFn, err := projects.GetTestProject(WithTimeout(5 * time.Second)); assert.NoError(t, err) {
+					unlockFn()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-statsDone
+	assert.Equal(t, 0, projects.Stats().Locked)
+}
+
+func TestRedisProjectLocker_ErrorsDoNotPanic(t *testing.T) {
+	t.Parallel()
+	errs := make(chan error, 2)
+	rl := &redisProjectLocker{
+		redisLocker: &redisLocker{
+			ttl: 40 * time.Millisecond,
+			onError: func(err error) {
+				errs <- err
+			},
+		},
+		projectID: "connection.keboola.com-1234",
+		redisLock: &fakeRedisLock{
+			refreshErr: errors.New("connection refused"),
+			releaseErr: errors.New("lock not held"),
+		},
+		locked: true,
+	}
+
+	// Extension error is reported
+	ctx, cancel := context.WithCancel(context.Background())
+	rl.cancel = cancel
+	assert.NotPanics(t, func() {
+		rl.extendLock(ctx)
+	})
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, `redis lock of test project "connection.keboola.com-1234" expired: cannot extend the redis lock: connection refused`)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout")
+	}
+
+	// Release error is reported
+	assert.NotPanics(t, func() {
+		rl.release()
+	})
+	assert.EqualError(t, <-errs, `cannot unlock test project using redis lock: lock not held`)
+	assert.False(t, rl.isLocked())
+}
+
+// flakyRedisLock implements redisLock, the first refreshes fail.
+type flakyRedisLock struct {
+	lock      sync.Mutex
+	failures  int
+	refreshes int
+}
+
+func (l *flakyRedisLock) Refresh(_ context.Context, _ time.Duration, _ *redislock.Options) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refreshes++
+	if l.refreshes <= l.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (l *flakyRedisLock) Release(_ context.Context) error {
+	return nil
+}
+
+func (l *flakyRedisLock) refreshCount() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.refreshes
+}
+
+func TestRedisProjectLocker_ExtensionRetried(t *testing.T) {
+	t.Parallel()
+	errs := make(chan error, 10)
+	lock := &flakyRedisLock{failures: 2}
+	rl := &redisProjectLocker{
+		redisLocker: &redisLocker{ttl: 40 * time.Millisecond, onError: func(err error) { errs <- err }},
+		projectID:   "connection.keboola.com-1234",
+		redisLock:   lock,
+		locked:      true,
+		expires:     time.Now().Add(time.Minute),
+	}
+
+	// Failed extensions are reported and retried, the lock is not expired yet
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rl.extendLock(ctx)
+	assert.EqualError(t, <-errs, `cannot extend the redis lock: connection refused`)
+	assert.EqualError(t, <-errs, `cannot extend the redis lock: connection refused`)
+	assert.Eventually(t, func() bool { return lock.refreshCount() >= 3 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, (&Project{locker: rl}).LockErr())
+}
+
+func TestRedisProjectLocker_ExtensionExpired(t *testing.T) {
+	t.Parallel()
+	errs := make(chan error, 10)
+	rl := &redisProjectLocker{
+		redisLocker: &redisLocker{ttl: 40 * time.Millisecond, onError: func(err error) { errs <- err }},
+		projectID:   "connection.keboola.com-1234",
+		redisLock:   &flakyRedisLock{failures: 1000},
+		locked:      true,
+		expires:     time.Now().Add(25 * time.Millisecond),
+	}
+
+	// Extension is retried until the lock expires
+	rl.extendLock(context.Background())
+	close(errs)
+	var reported []string
+	for err := range errs {
+		reported = append(reported, err.Error())
+	}
+	require.GreaterOrEqual(t, len(reported), 2)
+	assert.Equal(t, `cannot extend the redis lock: connection refused`, reported[0])
+	assert.Equal(t, `redis lock of test project "connection.keboola.com-1234" expired: cannot extend the redis lock: connection refused`, reported[len(reported)-1])
+
+	// The holder can check the lock
+	p := &Project{definition: Definition{ProjectID: 1234}, locker: rl}
+	assert.EqualError(t, p.LockErr(), reported[len(reported)-1])
+}
+
+func TestGetProjectsFromFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "projects1.json")
+	path2 := filepath.Join(dir, "projects2.json")
+	require.NoError(t, os.WriteFile(path1, []byte(`[{"project": 1009,"backend":"snowflake", "host": "files.keboola.com", "token": "bar", "stagingStorage": "s3"}]`), 0o600))
+	require.NoError(t, os.WriteFile(path2, []byte(`[{"project": 1010,"backend":"bigquery", "host": "files.keboola.com", "token": "bar", "stagingStorage": "gcs"}]`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte(`foo`), 0o600))
+
+	// Files
+	projects, err := GetProjectsFromFiles([]string{path1, path2}, WithLockErrorHandler(func(err error) {}))
+	require.NoError(t, err)
+	assert.Len(t, projects, 2)
+	project, unlockFn, err := projects.GetTestProject(WithBigQueryBackend())
+	require.NoError(t, err)
+	assert.Equal(t, 1010, project.ID())
+	unlockFn()
+
+	// Dir
+	projects, err = GetProjectsFromDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, projects, 2)
+}
+
+func TestGetProjectsFromFiles_DuplicateID(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "projects1.json")
+	path2 := filepath.Join(dir, "projects2.json")
+	require.NoError(t, os.WriteFile(path1, []byte(`[{"project": 1011,"backend":"snowflake", "host": "files.keboola.com", "token": "bar", "stagingStorage": "s3"}]`), 0o600))
+	require.NoError(t, os.WriteFile(path2, []byte(`[{"project": 1011,"backend":"bigquery", "host": "files.keboola.com", "token": "bar", "stagingStorage": "gcs"}]`), 0o600))
+
+	_, err := GetProjectsFromFiles([]string{path1, path2})
+	assert.EqualError(t, err, fmt.Sprintf(`duplicate project "1011" found in "%s" and "%s"`, path1, path2))
+
+	_, err = GetProjectsFromDir(dir)
+	assert.EqualError(t, err, fmt.Sprintf(`duplicate project "1011" found in "%s" and "%s"`, path1, path2))
+}
+
+func TestGetProjectsFromDir_Empty(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	_, err := GetProjectsFromDir(dir)
+	assert.EqualError(t, err, fmt.Sprintf(`no JSON file found in projects dir "%s"`, dir))
+}
+
+func TestResetPool(t *testing.T) {
+	// Test modifies the global pool, so it cannot run in parallel
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "projects1.json")
+	path2 := filepath.Join(dir, "projects2.json")
+	require.NoError(t, os.WriteFile(path1, []byte(`[{"project": 1012,"backend":"snowflake", "host": "reset.keboola.com", "token": "bar", "stagingStorage": "s3"}]`), 0o600))
+	require.NoError(t, os.WriteFile(path2, []byte(`[{"project": 1013,"backend":"snowflake", "host": "reset.keboola.com", "token": "bar", "stagingStorage": "s3"}]`), 0o600))
+	t.Cleanup(ResetPool)
+
+	ResetPool()
+	project1, unlockFn1, err := GetTestProjectInPath(path1)
+	require.NoError(t, err)
+	assert.Equal(t, 1012, project1.ID())
+	unlockFn1()
+
+	// Pool is cached, the path is ignored
+	project2, unlockFn2, err := GetTestProjectInPath(path2)
+	require.NoError(t, err)
+	assert.Equal(t, 1012, project2.ID())
+	unlockFn2()
+
+	// Pool is loaded again after reset
+	ResetPool()
+	project3, unlockFn3, err := GetTestProjectInPath(path2)
+	require.NoError(t, err)
+	assert.Equal(t, 1013, project3.ID())
+	unlockFn3()
+}
+
+func TestLockTTL(t *testing.T) {
+	// Test modifies environment, so it cannot run in parallel
+	t.Setenv(TestKbcProjectsLockTTLKey, "")
+	ttl, err := lockTTL()
+	require.NoError(t, err)
+	assert.Equal(t, TTL, ttl)
+
+	t.Setenv(TestKbcProjectsLockTTLKey, "5m")
+	ttl, err = lockTTL()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, ttl)
+
+	t.Setenv(TestKbcProjectsLockTTLKey, "foo")
+	_, err = lockTTL()
+	assert.EqualError(t, err, `invalid TEST_KBC_PROJECTS_LOCK_TTL value "foo": time: invalid duration "foo"`)
+
+	t.Setenv(TestKbcProjectsLockTTLKey, "-1s")
+	_, err = lockTTL()
+	assert.EqualError(t, err, `invalid TEST_KBC_PROJECTS_LOCK_TTL value "-1s": must be positive`)
+
+	t.Setenv(TestKbcProjectsLockTTLKey, "3ns")
+	_, err = lockTTL()
+	assert.EqualError(t, err, `invalid TEST_KBC_PROJECTS_LOCK_TTL value "3ns": must be at least 1s`)
+}
+
+func TestRedisProjectLocker_RefreshUsesConfiguredTTL(t *testing.T) {
+	t.Parallel()
+	lock := &fakeRedisLock{refreshErr: errors.New("stop")}
+	rl := &redisProjectLocker{
+		redisLocker: &redisLocker{ttl: 40 * time.Millisecond, onError: func(err error) {}},
+		redisLock:   lock,
+		locked:      true,
+	}
+	rl.extendLock(context.Background())
+	assert.Equal(t, 40*time.Millisecond, lock.refreshedTTL)
+}
+
+func TestRedisProjectLocker_ObtainUsesConfiguredTTL(t *testing.T) {
+	t.Parallel()
+	host := os.Getenv(TestKbcProjectsLockHostKey)         // nolint: forbidigo
+	password := os.Getenv(TestKbcProjectsLockPasswordKey) // nolint: forbidigo
+	if host == "" || password == "" {
+		t.Skip("no redis credentials provided")
+	}
+
+	locker, err := newRedisLocker(host, password, 10*time.Second)
+	require.NoError(t, err)
+	rl := locker.newForProject(&Project{definition: Definition{Host: "ttl.keboola.com", ProjectID: 1014}}).(*redisProjectLocker)
+	token, ok := rl.tryLock()
+	require.True(t, ok)
+	defer rl.unlock(token)
+
+	ttl, err := rl.redisLock.(*redislock.Lock).TTL(context.Background())
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, 10*time.Second)
+}
+
+func TestProjectsPool_HealthCheck_FsLocker(t *testing.T) {
+	t.Parallel()
+	projects := MustGetProjectsFrom(projectsForTest())
+	assert.NoError(t, projects.HealthCheck(context.Background()))
+}
+
+func TestProjectsPool_HealthCheck_Errors(t *testing.T) {
+	t.Parallel()
+
+	// Redis client with an unreachable host
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	locker := &redisLocker{redisClient: client, locker: redislock.New(client), ttl: TTL}
+
+	projects := make(ProjectsPool, 0)
+	for _, def := range []Definition{
+		{Host: "health.keboola.com", Token: "bar", Backend: BackendSnowflake, StagingStorage: StagingStorageS3, ProjectID: 1015},
+		{Host: "health.keboola.com", Backend: BackendSnowflake, StagingStorage: StagingStorageS3, ProjectID: 1016},
+	} {
+		p := &Project{definition: def}
+		p.locker = locker.newForProject(p)
+		projects = append(projects, p)
+	}
+
+	err := projects.HealthCheck(context.Background())
+	if assert.Error(t, err) {
+		lines := strings.Split(err.Error(), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `locker is not available: dial tcp 127.0.0.1:1:`)
+		assert.Equal(t, `project "1016" is invalid: Key: 'Definition.Token' Error:Field validation for 'Token' failed on the 'required' tag`, lines[1])
+	}
+}
+
+func TestGetTestProject_InlineEnv(t *testing.T) {
+	// Test modifies environment and the global pool, so it cannot run in parallel
+	t.Setenv(TestKbcProjectsFileKey, "")
+	t.Setenv(TestKbcProjectsKey, `[{"project": 1017,"backend":"snowflake", "host": "inline.keboola.com", "token": "bar", "stagingStorage": "s3"}]`)
+	ResetPool()
+	t.Cleanup(ResetPool)
+
+	project, unlockFn, err := GetTestProject()
+	require.NoError(t, err)
+	defer unlockFn()
+	assert.Equal(t, 1017, project.ID())
+}
+
+func TestGetTestProject_NoEnv(t *testing.T) {
+	// Test modifies environment and the global pool, so it cannot run in parallel
+	t.Setenv(TestKbcProjectsFileKey, "")
+	t.Setenv(TestKbcProjectsKey, "")
+	ResetPool()
+	t.Cleanup(ResetPool)
+
+	_, err := getProjects("")
+	assert.EqualError(t, err, `please set TEST_KBC_PROJECTS_FILE or TEST_KBC_PROJECTS environment variable`)
+}
+
+func TestProjectsPool_Definitions(t *testing.T) {
+	t.Parallel()
+	var expected []Definition
+	require.NoError(t, json.Unmarshal([]byte(projectsForTest()), &expected))
+
+	projects := MustGetProjectsFrom(projectsForTest())
+	defs := projects.Definitions()
+	assert.Equal(t, expected, defs)
+
+	// No project is locked
+	assert.Equal(t, 0, projects.Stats().Locked)
+
+	// Definitions are copies
+	defs[0].Token = "modified"
+	assert.Equal(t, expected[0].Token, projects.Definitions()[0].Token)
+}
+
+func TestProjectsPool_Definitions_Labels(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[{"project": 1027,"backend":"snowflake", "host": "definitions.keboola.com", "token": "bar", "stagingStorage": "s3", "labels": {"region": "eu"}}]`)
+	require.NoError(t, err)
+
+	// Labels of the returned definitions are copies
+	defs := projects.Definitions()
+	defs[0].Labels["region"] = "us"
+	assert.Equal(t, map[string]string{"region": "eu"}, projects.Definitions()[0].Labels)
+	_, _, err = projects.GetTestProject(WithLabel("region", "us"))
+	assert.EqualError(t, err, `no compatible test project found (label region=us)`)
+}
+
+func TestProjectsPool_ReleaseAll(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[
+  {"project": 1018,"backend":"snowflake", "host": "release.keboola.com", "token": "bar", "stagingStorage": "s3"},
+  {"project": 1019,"backend":"snowflake", "host": "release.keboola.com", "token": "bar", "stagingStorage": "s3"}
+]`)
+	require.NoError(t, err)
+
+	// Lock both projects
+	_, unlockFn1, err := projects.GetTestProject()
+	require.NoError(t, err)
+	_, unlockFn2, err := projects.GetTestProject()
+	require.NoError(t, err)
+	assert.Equal(t, 2, projects.Stats().Locked)
+
+	// Release all
+	projects.ReleaseAll()
+	assert.Equal(t, 0, projects.Stats().Locked)
+
+	// Repeated calls and leaked unlock functions do not panic
+	assert.NotPanics(t, func() {
+		projects.ReleaseAll()
+		unlockFn1()
+		unlockFn2()
+	})
+
+	// Projects can be locked again
+	_, unlockFn3, err := projects.GetTestProject(WithTimeout(time.Second))
+	require.NoError(t, err)
+	defer unlockFn3()
+	_, unlockFn4, err := projects.GetTestProject(WithTimeout(time.Second))
+	require.NoError(t, err)
+	defer unlockFn4()
+}
+
+func TestProjectsPool_ReleaseAll_StaleUnlockFn(t *testing.T) {
+	t.Parallel()
+	fsProjects, err := GetProjectsFrom(`[{"project": 1030,"backend":"snowflake", "host": "stale.keboola.com", "token": "bar", "stagingStorage": "s3"}]`)
+	require.NoError(t, err)
+	cases := []struct {
+		name     string
+		projects ProjectsPool
+		opts     []Option
+	}{
+		{name: "fs exclusive", projects: fsProjects},
+		{name: "fs shared", projects: fsProjects, opts: []Option{WithSharedReadAccess()}},
+		{name: "redis", projects: newFakeRedisPool(time.Minute, 1031)},
+	}
+	for _, tc := range cases {
+		// Lock the project, release it and lock it again
+		_, staleUnlockFn, err := tc.projects.GetTestProject(tc.opts...)
+		require.NoError(t, err, tc.name)
+		tc.projects.ReleaseAll()
+		_, unlockFn, err := tc.projects.GetTestProject(tc.opts...)
+		require.NoError(t, err, tc.name)
+
+		// The stale unlock function doesn't unlock the new holder
+		staleUnlockFn()
+		assert.Equal(t, 1, tc.projects.Stats().Locked, tc.name)
+
+		unlockFn()
+		assert.Equal(t, 0, tc.projects.Stats().Locked, tc.name)
+	}
+}
+
+func TestProject_RefreshLock_FsLocker(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[{"project": 1020,"backend":"snowflake", "host": "refresh.keboola.com", "token": "bar", "stagingStorage": "s3"}]`)
+	require.NoError(t, err)
+
+	project, unlockFn, err := projects.GetTestProject()
+	require.NoError(t, err)
+	defer unlockFn()
+
+	// File system lock never expires
+	assert.ErrorIs(t, project.RefreshLock(context.Background()), ErrLockWithoutTTL)
+	_, ok := project.LockExpiresAt()
+	assert.False(t, ok)
+}
+
+func TestProject_RefreshLock_RedisLocker(t *testing.T) {
+	t.Parallel()
+	lock := &fakeRedisLock{}
+	rl := &redisProjectLocker{
+		redisLocker: &redisLocker{ttl: time.Hour, onError: func(err error) {}},
+		redisLock:   lock,
+		locked:      true,
+	}
+	project := &Project{definition: Definition{ProjectID: 1234}, locker: rl}
+
+	// Refresh extends the lease
+	before := time.Now()
+	require.NoError(t, project.RefreshLock(context.Background()))
+	assert.Equal(t, time.Hour, lock.refreshedTTL)
+	expiresAt, ok := project.LockExpiresAt()
+	assert.True(t, ok)
+	assert.False(t, expiresAt.Before(before.Add(time.Hour)))
+
+	// Refresh error is returned
+	lock.refreshErr = errors.New("lock not held")
+	assert.EqualError(t, project.RefreshLock(context.Background()), `cannot extend the redis lock: lock not held`)
+	expiresAtAfterErr, _ := project.LockExpiresAt()
+	assert.Equal(t, expiresAt, expiresAtAfterErr)
+}
+
+func TestGetTestProject_WithLabel(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[
+  {"project": 1021,"backend":"snowflake", "host": "labels.keboola.com", "token": "bar", "stagingStorage": "s3", "labels": {"region": "us"}},
+  {"project": 1022,"backend":"snowflake", "host": "labels.keboola.com", "token": "bar", "stagingStorage": "s3", "labels": {"region": "eu", "has-sandbox": "true"}}
+]`)
+	require.NoError(t, err)
+
+	// Project with matching labels
+	project, unlockFn, err := projects.GetTestProject(WithLabel("has-sandbox", "true"), WithLabel("region", "eu"))
+	require.NoError(t, err)
+	defer unlockFn()
+	assert.Equal(t, 1022, project.ID())
+
+	// No project with matching labels
+	_, _, err = projects.GetTestProject(WithLabel("region", "us"), WithLabel("has-sandbox", "true"))
+	assert.EqualError(t, err, `no compatible test project found (label has-sandbox=true, label region=us)`)
+	_, _, err = projects.GetTestProject(WithLabel("unknown", "value"))
+	assert.EqualError(t, err, `no compatible test project found (label unknown=value)`)
+}
+
+func TestGetTestProject_WithSharedReadAccess(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[{"project": 1023,"backend":"snowflake", "host": "shared.keboola.com", "token": "bar", "stagingStorage": "s3"}]`)
+	require.NoError(t, err)
+
+	// Two readers hold the project at the same time
+	type result struct {
+		project  *Project
+		unlockFn UnlockFn
+		err      error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			project, unlockFn, err := projects.GetTestProject(WithSharedReadAccess(), WithTimeout(time.Second))
+			results <- result{project: project, unlockFn: unlockFn, err: err}
+		}()
+	}
+	reader1 := <-results
+	reader2 := <-results
+	require.NoError(t, reader1.err)
+	require.NoError(t, reader2.err)
+	assert.Equal(t, 1023, reader1.project.ID())
+	assert.Same(t, reader1.project, reader2.project)
+	assert.Equal(t, 1, projects.Stats().Locked)
+
+	// Exclusive access waits for all readers
+	_, _, err = projects.GetTestProject(WithTimeout(100 * time.Millisecond))
+	assert.EqualError(t, err, `no test project became available within 100ms`)
+	reader1.unlockFn()
+	_, _, err = projects.GetTestProject(WithTimeout(100 * time.Millisecond))
+	assert.EqualError(t, err, `no test project became available within 100ms`)
+	reader2.unlockFn()
+	project, unlockFn, err := projects.GetTestProject(WithTimeout(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1023, project.ID())
+
+	// Reader waits for the exclusive access
+	_, _, err = projects.GetTestProject(WithSharedReadAccess(), WithTimeout(100*time.Millisecond))
+	assert.EqualError(t, err, `no test project became available within 100ms`)
+	unlockFn()
+
+	// ReleaseAll releases all readers
+	_, _, err = projects.GetTestProject(WithSharedReadAccess())
+	require.NoError(t, err)
+	_, _, err = projects.GetTestProject(WithSharedReadAccess())
+	require.NoError(t, err)
+	projects.ReleaseAll()
+	assert.Equal(t, 0, projects.Stats().Locked)
+	_, unlockFn, err = projects.GetTestProject(WithTimeout(time.Second))
+	require.NoError(t, err)
+	unlockFn()
+}
+
+func TestGetTestProject_WithSelection(t *testing.T) {
+	t.Parallel()
+	projects, err := GetProjectsFrom(`[
+  {"project": 1024,"backend":"snowflake", "host": "selection.keboola.com", "token": "bar", "stagingStorage": "s3"},
+  {"project": 1025,"backend":"snowflake", "host": "selection.keboola.com", "token": "bar", "stagingStorage": "s3"},
+  {"project": 1026,"backend":"snowflake", "host": "selection.keboola.com", "token": "bar", "stagingStorage": "s3"}
+]`)
+	require.NoError(t, err)
+
+	acquire := func(opts ...Option) int {
+		project, unlockFn, err := projects.GetTestProject(opts...)
+		require.NoError(t, err)
+		defer unlockFn()
+		return project.ID()
+	}
+
+	// InOrder always starts with the first project
+	assert.Equal(t, 1024, acquire())
+	assert.Equal(t, 1024, acquire(WithSelection(InOrder)))
+
+	// RoundRobin cycles through all projects
+	ids := make(map[int]bool)
+	previous := acquire(WithSelection(RoundRobin))
+	ids[previous] = true
+	for i := 0; i < 5; i++ {
+		id := acquire(WithSelection(RoundRobin))
+		assert.NotEqual(t, previous, id)
+		ids[id] = true
+		previous = id
+	}
+	assert.Len(t, ids, 3)
+
+	// Random returns a compatible project
+	assert.Contains(t, []int{1024, 1025, 1026}, acquire(WithSelection(Random)))
+	assert.Equal(t, 1025, acquire(WithSelection(Random), WithProjectID(1025)))
+}
@@ -9,35 +9,120 @@
 //	  %a: One or more of anything (character or white space) including the end of line character.
 //	  %A: Zero or more of anything (character or white space) including the end of line character.
 //	  %w: Zero or more white space characters.
-//	  %i: A signed integer value, for example +3142, -3142.
+//	  %i: A signed integer value, the sign is optional, for example 3142, +3142, -3142.
 //	  %d: An unsigned integer value, for example 123456.
 //	  %x: One or more hexadecimal character. That is, characters in the range 0-9, a-f, A-F.
 //	  %f: A floating point number, for example: 3.142, -3.142, 3.142E-10, 3.142e+10.
 //	  %c: A single character of any sort.
+//	  %json: A valid JSON object or array, for example {"id": 123}.
 //	  %%: A literal percent character: %.
+//
+// Custom wildcards can be registered by RegisterWildcard function.
+//
+// Wildcards %s, %S, %a, %A, %w, %d, %x and %c can be used with a count:
+//
+//	%3d:   Exactly 3 repetitions, for example 3 digits.
+//	%2,4s: 2 to 4 repetitions, for example 2-4 characters.
+//
+// Wildcard can be named as %{name:d}, see MatchCapture function.
 package wildcards
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pmezard/go-difflib/difflib"
 	"github.com/stretchr/testify/assert"
 )
 
+// registry maps a wildcard token to the regexp, see RegisterWildcard function.
+// Inspired by PhpUnit "assertStringMatchesFormat"
+// https://phpunit.readthedocs.io/en/9.5/assertions.html#assertstringmatchesformat
+var registry = map[string]string{
+	// %e: Represents a directory separator, for example / on Linux.
+	`%e`: regexp.QuoteMeta(string(os.PathSeparator)), // nolint forbidigo
+	// %s: One or more of anything (character or white space) except the end of line character.
+	`%s`: `.+`,
+	// %S: Zero or more of anything (character or white space) except the end of line character.
+	`%S`: `.*`,
+	// %a: One or more of anything (character or white space) including the end of line character.
+	`%a`: `(.|\n)+`,
+	// %A: Zero or more of anything (character or white space) including the end of line character.
+	`%A`: `(.|\n)*`,
+	// %w: Zero or more white space characters.
+	`%w`: `\s*`,
+	// %i: A signed integer value, the sign is optional, for example 3142, +3142, -3142.
+	`%i`: `[+-]?\d+`,
+	// %d: An unsigned integer value, for example 123456.
+	`%d`: `\d+`,
+	// %x: One or more hexadecimal character. That is, characters in the range 0-9, a-f, A-F.
+	`%x`: `[0-9a-fA-F]+`,
+	// %f: A floating point number, for example: 3.142, -3.142, 3.142E-10, 3.142e+10.
+	`%f`: `[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?`,
+	// %c: A single character of any sort.
+	`%c`: `.`,
+	// %json: A valid JSON object or array, for example {"id": 123}.
+	// The regexp is replaced by the valid JSON regions of the actual text before the match, see matchRegexp.
+	`%json`: jsonGroupExpr,
+	// %%: A literal percent character: %.
+	`%%`: `%`,
+}
+
+// tokens contains keys of the registry, sorted from the longest.
+var tokens = []string{`%json`, `%e`, `%s`, `%S`, `%a`, `%A`, `%w`, `%i`, `%d`, `%x`, `%f`, `%c`, `%%`}
+
+var registryLock sync.RWMutex
+
+// repeatable maps a wildcard token, that can be used with a count, to the regexp of one repetition.
+// For example, %3d means exactly 3 digits and %2,4s means 2-4 characters.
+var repeatable = map[string]string{
+	`%s`: `.`,
+	`%S`: `.`,
+	`%a`: `(.|\n)`,
+	`%A`: `(.|\n)`,
+	`%w`: `\s`,
+	`%d`: `\d`,
+	`%x`: `[0-9a-fA-F]`,
+	`%c`: `.`,
+}
+
+// jsonGroupName is name of the regexp group used by the %json wildcard, the group is not returned by MatchCapture.
+const jsonGroupName = `wildcardsJSON`
+
+// jsonGroupExpr is the regexp of the %json wildcard, see withJSONRegions.
+const jsonGroupExpr = `(?P<` + jsonGroupName + `>[\[{](?:.|\n)*[\]}])`
+
+// maxRepeatCount is the maximum count supported by the regexp package.
+const maxRepeatCount = 1000
+
+var (
+	diffHeaderRegexp   = regexp.MustCompile(`^ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+	namedCaptureRegexp = regexp.MustCompile(`^%\{([a-zA-Z_][a-zA-Z0-9_]*):([^{}]+)\}`)
+	countRegexp        = regexp.MustCompile(`^%(\d+)(?:,(\d+))?([a-zA-Z])`)
+)
+
+// Options for the CompareWithOptions and AssertWithOptions functions.
+type Options struct {
+	// MaxDiffLines limits the reported diff to the first n changed hunks, 0 means no limit.
+	// The texts are always compared as a whole, only the diff in the error message is shortened.
+	MaxDiffLines int
+}
+
 // Compare compares two texts and allows using wildcards in expected value, see ToRegexp function.
 func Compare(expected string, actual string) error {
-	expected = strings.TrimSpace(expected)
-	actual = strings.TrimSpace(actual)
-
-	// Replace NBSP with space
-	actual = strings.ReplaceAll(actual, " ", " ")
+	return CompareWithOptions(expected, actual, Options{})
+}
 
-	// Remove \r chars
-	actual = strings.ReplaceAll(actual, "\r", "")
+// CompareWithOptions compares two texts and allows using wildcards in expected value, see Compare function and Options.
+func CompareWithOptions(expected string, actual string, opts Options) error {
+	expected = strings.TrimSpace(expected)
+	actual = normalize(strings.TrimSpace(actual))
 
 	// Assert
 	if len(expected) == 0 {
@@ -46,20 +131,71 @@ func Compare(expected string, actual string) error {
 		}
 	} else {
 		expectedRegexp := ToRegexp(strings.TrimSpace(expected))
-		diff := difflib.UnifiedDiff{
-			A: difflib.SplitLines(EscapeWhitespaces(expected)),
-			B: difflib.SplitLines(EscapeWhitespaces(actual)),
-		}
-		diffStr, _ := difflib.GetUnifiedDiffString(diff)
-		diffStr = cleanDiffOutput(diffStr)
+		diffStr := limitDiff(CleanDiff(expected, actual), opts.MaxDiffLines)
 		r := regexp.MustCompile("^" + expectedRegexp + "$")
-		if !r.MatchString(actual) {
+		if !matchRegexp(r, actual) {
 			return fmt.Errorf("Diff:\n-----\n%s-----\nActual:\n-----\n%s\n-----\nExpected:\n-----\n%v\n-----\n", diffStr, actual, expected) //lint:ignore ST1005 We want to end with a newline
 		}
 	}
 	return nil
 }
 
+// CleanDiff returns unified diff of two texts, wildcards are allowed in expected value, see ToRegexp function.
+// Diff blocks in which the actual lines match the wildcards are omitted, so an empty string is returned for matching texts.
+// Whitespaces are escaped, see EscapeWhitespaces function.
+func CleanDiff(expected string, actual string) string {
+	expected = strings.TrimSpace(expected)
+	actual = normalize(strings.TrimSpace(actual))
+	diff := difflib.UnifiedDiff{
+		A: difflib.SplitLines(EscapeWhitespaces(expected)),
+		B: difflib.SplitLines(EscapeWhitespaces(actual)),
+	}
+	diffStr, _ := difflib.GetUnifiedDiffString(diff)
+	return cleanDiffOutput(diffStr)
+}
+
+// LineDiff is a block of different lines found by Diff function.
+// Line numbers start from 1, if there are no lines, the number points to the line after which the lines are missing.
+type LineDiff struct {
+	ExpectedLine int
+	ActualLine   int
+	Expected     []string
+	Actual       []string
+	// Match is true if the lines are different, but the actual lines match the wildcards.
+	Match bool
+}
+
+// Diff compares two texts and allows using wildcards in expected value, see ToRegexp function.
+// It returns blocks of different lines and true, if the whole actual text matches the expected text.
+// Unlike Compare, the blocks that match the wildcards are returned too, see LineDiff.Match.
+func Diff(expected string, actual string) ([]LineDiff, bool) {
+	expected = strings.TrimSpace(expected)
+	actual = normalize(strings.TrimSpace(actual))
+
+	diff := difflib.UnifiedDiff{
+		A: difflib.SplitLines(EscapeWhitespaces(expected)),
+		B: difflib.SplitLines(EscapeWhitespaces(actual)),
+	}
+	diffStr, _ := difflib.GetUnifiedDiffString(diff)
+
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	out := make([]LineDiff, 0)
+	for _, block := range diffBlocks(diffStr) {
+		d := LineDiff{
+			ExpectedLine: block.expectedStart,
+			ActualLine:   block.actualStart,
+			Expected:     diffLines(expectedLines, block.expectedStart, block.expectedCount),
+			Actual:       diffLines(actualLines, block.actualStart, block.actualCount),
+		}
+		d.Match = matchRegexp(regexp.MustCompile("^"+ToRegexp(strings.Join(d.Expected, "\n"))+"$"), strings.Join(d.Actual, "\n"))
+		out = append(out, d)
+	}
+
+	match := matchRegexp(regexp.MustCompile("^"+ToRegexp(expected)+"$"), actual)
+	return out, match
+}
+
 // Assert compares two texts and allows using wildcards in expected value, see ToRegexp function.
 func Assert(t assert.TestingT, expected string, actual string, msgAndArgs ...any) bool {
 	err := Compare(expected, actual)
@@ -70,54 +206,275 @@ func Assert(t assert.TestingT, expected string, actual string, msgAndArgs ...any
 	return true
 }
 
+// AssertWithOptions compares two texts and allows using wildcards in expected value, see Assert function and Options.
+func AssertWithOptions(t assert.TestingT, expected string, actual string, opts Options, msgAndArgs ...any) bool {
+	err := CompareWithOptions(expected, actual, opts)
+	if err != nil {
+		assert.Fail(t, err.Error(), msgAndArgs...)
+		return false
+	}
+	return true
+}
+
+// CompareContains checks if the actual text contains the pattern with wildcards, see ToRegexp function.
+// The diff is not generated, because the pattern matches only a part of the actual text.
+func CompareContains(pattern string, actual string) error {
+	pattern = strings.TrimSpace(pattern)
+	actual = normalize(strings.TrimSpace(actual))
+	if !matchRegexp(regexp.MustCompile(ToRegexp(pattern)), actual) {
+		return fmt.Errorf("Actual:\n-----\n%s\n-----\nExpected to contain:\n-----\n%s\n-----\n", actual, pattern) //lint:ignore ST1005 We want to end with a newline
+	}
+	return nil
+}
+
+// AssertContains checks if the actual text contains the pattern with wildcards, see ToRegexp function.
+func AssertContains(t assert.TestingT, pattern string, actual string, msgAndArgs ...any) bool {
+	err := CompareContains(pattern, actual)
+	if err != nil {
+		assert.Fail(t, err.Error(), msgAndArgs...)
+		return false
+	}
+	return true
+}
+
+// AssertFile compares the actual text with the expected text loaded from the file, see Assert function.
+func AssertFile(t assert.TestingT, expectedPath string, actual string, msgAndArgs ...any) bool {
+	expected, err := os.ReadFile(expectedPath) // nolint forbidigo
+	if err != nil {
+		assert.Fail(t, fmt.Sprintf(`cannot read expected file "%s": %s`, expectedPath, err), msgAndArgs...)
+		return false
+	}
+	return Assert(t, string(expected), actual, msgAndArgs...)
+}
+
+// Match checks if the actual text matches the pattern with wildcards, see ToRegexp function.
+func Match(pattern string, actual string) bool {
+	return MatchError(pattern, actual) == nil
+}
+
+// MatchContains checks if the actual text contains the pattern with wildcards, see ToRegexp function.
+func MatchContains(pattern string, actual string) bool {
+	r, err := regexp.Compile(ToRegexp(pattern))
+	if err != nil {
+		return false
+	}
+	return matchRegexp(r, normalize(actual))
+}
+
+// MatchError checks if the actual text matches the pattern with wildcards, see ToRegexp function.
+// Unlike Compare, the texts are not trimmed and no diff is generated.
+func MatchError(pattern string, actual string) error {
+	r, err := regexp.Compile("^" + ToRegexp(pattern) + "$")
+	if err != nil {
+		return fmt.Errorf(`invalid pattern "%s": %w`, pattern, err)
+	}
+	if !matchRegexp(r, normalize(actual)) {
+		return fmt.Errorf(`text "%s" does not match pattern "%s"`, actual, pattern)
+	}
+	return nil
+}
+
+// RegisterWildcard registers a custom wildcard token, for example "%uuid", and the regexp it represents.
+// The token must start with "%", the longest registered token matching the input is used.
+// Tokens conflicting with the count or named capture syntax, for example "%3x" or "%{x", are rejected.
+func RegisterWildcard(token string, expr string) error {
+	if len(token) < 2 || !strings.HasPrefix(token, "%") {
+		return fmt.Errorf(`wildcard "%s" must start with "%%" followed by at least one character`, token)
+	}
+	// The count and named capture syntax is parsed first, so such a token could never match
+	if countRegexp.MatchString(token) || namedCaptureRegexp.MatchString(token) || strings.Contains(token, "{") {
+		return fmt.Errorf(`wildcard "%s" conflicts with the count or named capture syntax`, token)
+	}
+	if _, err := regexp.Compile(expr); err != nil {
+		return fmt.Errorf(`wildcard "%s" has invalid regexp "%s": %w`, token, expr, err)
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, found := registry[token]; found {
+		return fmt.Errorf(`wildcard "%s" is already registered`, token)
+	}
+	registry[token] = expr
+	tokens = append(tokens, token)
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return len(tokens[i]) > len(tokens[j])
+	})
+	return nil
+}
+
+// MatchCapture checks if the actual text matches the pattern with wildcards, see ToRegexp function.
+// On success, it returns values of named wildcards, for example "%{id:d}" captures an integer as "id".
+func MatchCapture(pattern string, actual string) (map[string]string, bool) {
+	r, err := regexp.Compile("^" + ToRegexp(pattern) + "$")
+	if err != nil {
+		return nil, false
+	}
+	actual = normalize(actual)
+	m := withJSONRegions(r, actual).FindStringSubmatch(actual)
+	if m == nil {
+		return nil, false
+	}
+	out := make(map[string]string)
+	for i, name := range r.SubexpNames() {
+		if name != "" && name != jsonGroupName {
+			out[name] = m[i]
+		}
+	}
+	return out, true
+}
+
+// matchRegexp checks if the actual text matches the regexp, the %json wildcard matches only a valid JSON region, see withJSONRegions.
+func matchRegexp(r *regexp.Regexp, actual string) bool {
+	return withJSONRegions(r, actual).MatchString(actual)
+}
+
+// withJSONRegions replaces the regexp of the %json wildcard by an alternation of the valid JSON regions of the actual text.
+// The regexp package cannot match balanced brackets, so the regions are found by jsonRegions in advance.
+func withJSONRegions(r *regexp.Regexp, actual string) *regexp.Regexp {
+	if r.SubexpIndex(jsonGroupName) == -1 {
+		return r
+	}
+	regions := jsonRegions(actual)
+	alternatives := make([]string, 0, len(regions))
+	for _, region := range regions {
+		alternatives = append(alternatives, regexp.QuoteMeta(region))
+	}
+	expr := `[^\x00-\x{10FFFF}]` // no region, nothing matches
+	if len(alternatives) > 0 {
+		expr = strings.Join(alternatives, "|")
+	}
+	return regexp.MustCompile(strings.ReplaceAll(r.String(), jsonGroupExpr, `(?P<`+jsonGroupName+`>`+expr+`)`))
+}
+
+// jsonRegions returns valid JSON objects and arrays found in the text, sorted from the longest.
+// A region starts with a bracket and ends with the matching bracket, brackets in JSON strings are skipped.
+func jsonRegions(text string) []string {
+	unique := make(map[string]bool)
+	for start := 0; start < len(text); start++ {
+		if text[start] != '{' && text[start] != '[' {
+			continue
+		}
+		if end := matchingBracket(text, start); end != -1 && validJSON(text[start:end+1]) {
+			unique[text[start:end+1]] = true
+		}
+	}
+	out := make([]string, 0, len(unique))
+	for region := range unique {
+		out = append(out, region)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i]) != len(out[j]) {
+			return len(out[i]) > len(out[j])
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// matchingBracket returns index of the bracket closing the bracket at the start index, or -1 if there is none.
+func matchingBracket(text string, start int) int {
+	depth := 0
+	inString := false
+	for i := start; i < len(text); i++ {
+		switch c := text[i]; {
+		case inString && c == '\\':
+			i++ // skip the escaped character
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// validJSON checks if the text is a valid JSON.
+// The diff lines are compared with escaped whitespaces, so the text is checked also with whitespaces restored, see EscapeWhitespaces.
+func validJSON(s string) bool {
+	if json.Valid([]byte(s)) {
+		return true
+	}
+	s = strings.NewReplacer(`→→→→`, "\t", `␣`, " ").Replace(s)
+	return json.Valid([]byte(s))
+}
+
 // ToRegexp converts string with wildcards to regexp, so it can be used in assert.Regexp.
 func ToRegexp(input string) string {
-	input = regexp.QuoteMeta(input)
-	re := regexp.MustCompile(`%.`)
-	return re.ReplaceAllStringFunc(input, func(s string) string {
-		// Inspired by PhpUnit "assertStringMatchesFormat"
-		// https://phpunit.readthedocs.io/en/9.5/assertions.html#assertstringmatchesformat
-		switch s {
-		// %e: Represents a directory separator, for example / on Linux.
-		case `%e`:
-			return regexp.QuoteMeta(string(os.PathSeparator)) // nolint forbidigo
-		// %s: One or more of anything (character or white space) except the end of line character.
-		case `%s`:
-			return `.+`
-		// %S: Zero or more of anything (character or white space) except the end of line character.
-		case `%S`:
-			return `.*`
-		// %a: One or more of anything (character or white space) including the end of line character.
-		case `%a`:
-			return `(.|\n)+`
-		// %A: Zero or more of anything (character or white space) including the end of line character.
-		case `%A`:
-			return `(.|\n)*`
-		// %w: Zero or more white space characters.
-		case `%w`:
-			return `\s*`
-		// %i: A signed integer value, for example +3142, -3142.
-		case `%i`:
-			return `(\+|\-)\d+`
-		// %d: An unsigned integer value, for example 123456.
-		case `%d`:
-			return `\d+`
-		// %x: One or more hexadecimal character. That is, characters in the range 0-9, a-f, A-F.
-		case `%x`:
-			return `[0-9a-zA-Z]+`
-		// %f: A floating point number, for example: 3.142, -3.142, 3.142E-10, 3.142e+10.
-		case `%f`:
-			return `[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?`
-		// %c: A single character of any sort.
-		case `%c`:
-			return `.`
-		// %%: A literal percent character: %.
-		case `%%`:
-			return `%`
-		}
-
-		return s
-	})
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var out strings.Builder
+	for len(input) > 0 {
+		// Copy literal text up to the next wildcard
+		i := strings.IndexByte(input, '%')
+		if i == -1 {
+			out.WriteString(regexp.QuoteMeta(input))
+			break
+		}
+		out.WriteString(regexp.QuoteMeta(input[:i]))
+		input = input[i:]
+
+		// Convert wildcard, unknown wildcard is kept as a literal text
+		expr, length := parseWildcard(input)
+		if length == 0 {
+			out.WriteString(regexp.QuoteMeta(input[:1]))
+			input = input[1:]
+			continue
+		}
+		out.WriteString(expr)
+		input = input[length:]
+	}
+	return out.String()
+}
+
+// parseWildcard converts wildcard at the beginning of the input to regexp.
+// It returns the regexp and length of the wildcard, or zero length if there is no known wildcard.
+func parseWildcard(input string) (expr string, length int) {
+	// Named capture, for example %{name:d}
+	if m := namedCaptureRegexp.FindStringSubmatch(input); m != nil {
+		if expr, length := parseWildcard("%" + m[2]); length == len(m[2])+1 {
+			return fmt.Sprintf(`(?P<%s>%s)`, m[1], expr), len(m[0])
+		}
+		return "", 0
+	}
+
+	// Wildcard with a count, for example %3d or %2,4s
+	if m := countRegexp.FindStringSubmatch(input); m != nil {
+		if expr, found := repeatable["%"+m[3]]; found {
+			if quantifier, ok := toQuantifier(m[1], m[2]); ok {
+				return expr + quantifier, len(m[0])
+			}
+		}
+		return "", 0
+	}
+
+	// Find the longest registered token
+	if token := lookupToken(input); token != "" {
+		return registry[token], len(token)
+	}
+	return "", 0
+}
+
+// toQuantifier converts count of a wildcard to the regexp quantifier, for example {3} or {2,4}.
+func toQuantifier(minStr, maxStr string) (string, bool) {
+	minCount, err := strconv.Atoi(minStr)
+	if err != nil || minCount > maxRepeatCount {
+		return "", false
+	}
+	if maxStr == "" {
+		return fmt.Sprintf(`{%d}`, minCount), true
+	}
+	maxCount, err := strconv.Atoi(maxStr)
+	if err != nil || maxCount > maxRepeatCount || maxCount < minCount {
+		return "", false
+	}
+	return fmt.Sprintf(`{%d,%d}`, minCount, maxCount), true
 }
 
 // EscapeWhitespaces escapes all whitespaces except new line -> for clearer difference in diff output.
@@ -137,6 +494,22 @@ func EscapeWhitespaces(input string) string {
 	})
 }
 
+// lookupToken returns the longest registered token at the beginning of the input or an empty string.
+func lookupToken(input string) string {
+	for _, token := range tokens {
+		if strings.HasPrefix(input, token) {
+			return token
+		}
+	}
+	return ""
+}
+
+// normalize replaces NBSP with space and removes \r chars.
+func normalize(input string) string {
+	input = strings.ReplaceAll(input, "\u00a0", " ")
+	return strings.ReplaceAll(input, "\r", "")
+}
+
 // cleanDiffOutput - if text doesn't match wildcards, then diff between <wildcards/text> is printed.
 // So we have to remove diff blocks that are false positive.
 //
@@ -147,16 +520,10 @@ func EscapeWhitespaces(input string) string {
 //	+Foo:␣bar4
 func cleanDiffOutput(in string) string {
 	var out strings.Builder
-	for _, block := range regexp.MustCompile(`(?m)^@@`).Split(in, -1) {
-		// Skip first line, eg. "@@ -4 +4 @@"
-		_, content, _ := strings.Cut(block, "\n")
-		if content == "" {
-			continue
-		}
-
+	for _, block := range diffBlocks(in) {
 		// Separate expected and actual block, find first "+" at line beginning.
 		var actual, expected string
-		parts := regexp.MustCompile(`(?m)^+`).Split(content, 2)
+		parts := regexp.MustCompile(`(?m)^+`).Split(block.content, 2)
 
 		// Remove "-" from each line in expected block, for example "-Foo:␣%s" -> "Foo:␣%s"
 		expected = regexp.MustCompile(`(?m)^-`).ReplaceAllString(parts[0], "")
@@ -167,10 +534,78 @@ func cleanDiffOutput(in string) string {
 		}
 
 		// Compare expected and actual, for example "Foo:␣%s" and "Foo:␣bar4"
-		if !regexp.MustCompile("^" + ToRegexp(expected) + "$").MatchString(actual) {
+		if !matchRegexp(regexp.MustCompile("^"+ToRegexp(expected)+"$"), actual) {
 			// Keep block with difference
-			out.WriteString("@@" + block)
+			out.WriteString(block.raw)
+		}
+	}
+	return out.String()
+}
+
+// diffBlock is one block of the unified diff, for example:
+//
+//	@@ -4 +4 @@
+//	-Foo:␣%s
+//	+Foo:␣bar4
+type diffBlock struct {
+	raw           string // raw is the whole block including the header
+	content       string // content is the block without the header
+	expectedStart int
+	expectedCount int
+	actualStart   int
+	actualCount   int
+}
+
+// diffBlocks splits the unified diff to blocks.
+func diffBlocks(in string) (out []diffBlock) {
+	for _, block := range regexp.MustCompile(`(?m)^@@`).Split(in, -1) {
+		// Skip first line, eg. "@@ -4 +4 @@"
+		header, content, _ := strings.Cut(block, "\n")
+		if content == "" {
+			continue
 		}
+
+		// Parse line numbers from the header
+		b := diffBlock{raw: "@@" + block, content: content}
+		if m := diffHeaderRegexp.FindStringSubmatch(header); m != nil {
+			b.expectedStart, b.expectedCount = parseDiffRange(m[1], m[2])
+			b.actualStart, b.actualCount = parseDiffRange(m[3], m[4])
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// limitDiff keeps the first max blocks of the unified diff, the number of omitted blocks is appended.
+func limitDiff(in string, max int) string {
+	blocks := diffBlocks(in)
+	if max <= 0 || len(blocks) <= max {
+		return in
+	}
+
+	var out strings.Builder
+	for _, block := range blocks[:max] {
+		out.WriteString(block.raw)
 	}
+	out.WriteString(fmt.Sprintf("... and %d more differences\n", len(blocks)-max))
 	return out.String()
 }
+
+// diffLines returns lines from the unified diff range, start is 1-based.
+func diffLines(lines []string, start, count int) []string {
+	out := make([]string, 0, count)
+	if count > 0 {
+		out = append(out, lines[start-1:start-1+count]...)
+	}
+	return out
+}
+
+// parseDiffRange parses the range from the unified diff header, for example "4" or "4,2".
+func parseDiffRange(startStr, countStr string) (start, count int) {
+	start, _ = strconv.Atoi(startStr) // \d+ is always integer
+	count = 1
+	if countStr != "" {
+		count, _ = strconv.Atoi(countStr)
+	}
+	return start, count
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -175,9 +176,9 @@ func TestWildcardToRegexp(t *testing.T) {
 		{in: `%a`, out: `(.|\n)+`},
 		{in: `%A`, out: `(.|\n)*`},
 		{in: `%w`, out: `\s*`},
-		{in: `%i`, out: `(\+|\-)\d+`},
+		{in: `%i`, out: `[+-]?\d+`},
 		{in: `%d`, out: `\d+`},
-		{in: `%x`, out: `[0-9a-zA-Z]+`},
+		{in: `%x`, out: `[0-9a-fA-F]+`},
 		{in: `%f`, out: `[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?`},
 		{in: `%c`, out: `.`},
 		{in: `%%`, out: `%`},
@@ -223,7 +224,8 @@ func TestWildcardToRegexpMatch(t *testing.T) {
 		{pattern: `%w`, input: ` `, match: true},
 		{pattern: `%w`, input: " \t\n", match: true},
 		{pattern: `%i`, input: ``, match: false},
-		{pattern: `%i`, input: `123`, match: false},
+		{pattern: `%i`, input: `123`, match: true},
+		{pattern: `%i`, input: `+-123`, match: false},
 		{pattern: `%i`, input: `+123`, match: true},
 		{pattern: `%i`, input: `-123`, match: true},
 		{pattern: `%d`, input: ``, match: false},
@@ -232,6 +234,9 @@ func TestWildcardToRegexpMatch(t *testing.T) {
 		{pattern: `%d`, input: `-123`, match: false},
 		{pattern: `%x`, input: ``, match: false},
 		{pattern: `%x`, input: `0af`, match: true},
+		{pattern: `%x`, input: `aF0`, match: true},
+		{pattern: `%x`, input: `g`, match: false},
+		{pattern: `%x`, input: `0ag`, match: false},
 		{pattern: `%f`, input: ``, match: false},
 		{pattern: `%f`, input: `12`, match: true},
 		{pattern: `%f`, input: `12.34`, match: true},
@@ -250,3 +255,410 @@ func TestWildcardToRegexpMatch(t *testing.T) {
 		assert.Equal(t, data.match, match, fmt.Sprintf(`pattern: "%s", input: "%s"`, data.pattern, data.input))
 	}
 }
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{pattern: ``, input: `foo`, match: false},
+		{pattern: ``, input: ``, match: true},
+		{pattern: `%e`, input: `foo`, match: false},
+		{pattern: `%e`, input: string(os.PathSeparator), match: true}, // nolint forbidigo
+		{pattern: `%s`, input: ``, match: false},
+		{pattern: `%s`, input: "\n", match: false},
+		{pattern: `%s`, input: `foo`, match: true},
+		{pattern: `%S`, input: "\n", match: false},
+		{pattern: `%S`, input: ``, match: true},
+		{pattern: `%a`, input: ``, match: false},
+		{pattern: `%a`, input: "\n", match: true},
+		{pattern: `%A`, input: ``, match: true},
+		{pattern: `%w`, input: " \t\n", match: true},
+		{pattern: `%i`, input: `+123`, match: true},
+		{pattern: `%i`, input: `123`, match: true},
+		{pattern: `%d`, input: `123`, match: true},
+		{pattern: `%d`, input: `-123`, match: false},
+		{pattern: `%x`, input: `0af`, match: true},
+		{pattern: `%f`, input: `-12.34`, match: true},
+		{pattern: `%c`, input: `aa`, match: false},
+		{pattern: `%c`, input: `a`, match: true},
+		{pattern: `%%`, input: `%`, match: true},
+		// NBSP and \r are normalized
+		{pattern: `foo bar`, input: "foo\u00a0bar", match: true},
+		{pattern: "foo\nbar", input: "foo\r\nbar", match: true},
+	}
+
+	for _, data := range cases {
+		desc := fmt.Sprintf(`pattern: "%s", input: "%s"`, data.pattern, data.input)
+		assert.Equal(t, data.match, Match(data.pattern, data.input), desc)
+		if data.match {
+			assert.NoError(t, MatchError(data.pattern, data.input), desc)
+		} else {
+			assert.Error(t, MatchError(data.pattern, data.input), desc)
+		}
+	}
+}
+
+func TestMatchError(t *testing.T) {
+	t.Parallel()
+	err := MatchError(`foo %d`, `foo bar`)
+	if assert.Error(t, err) {
+		assert.Equal(t, `text "foo bar" does not match pattern "foo %d"`, err.Error())
+	}
+}
+
+func TestRegisterWildcard(t *testing.T) {
+	t.Parallel()
+
+	// Register custom tokens
+	assert.NoError(t, RegisterWildcard(`%uuid`, `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`))
+	assert.NoError(t, RegisterWildcard(`%datetime`, `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`))
+
+	// Custom tokens
+	assert.True(t, Match(`id: %uuid`, `id: 123e4567-e89b-12d3-a456-426614174000`))
+	assert.False(t, Match(`id: %uuid`, `id: 123`))
+	assert.True(t, Match(`created: %datetime`, `created: 2022-01-02T03:04:05Z`))
+	assert.False(t, Match(`created: %datetime`, `created: 2022-01-02`))
+
+	// The longest token is used, built-in tokens still work
+	assert.True(t, Match(`%datetime, %d, %s`, `2022-01-02T03:04:05Z, 123, foo`))
+	assert.Equal(t, `\d+\.\+`, ToRegexp(`%d.+`))
+
+	// Invalid tokens
+	err := RegisterWildcard(`uuid`, `.+`)
+	if assert.Error(t, err) {
+		assert.Equal(t, `wildcard "uuid" must start with "%" followed by at least one character`, err.Error())
+	}
+	err = RegisterWildcard(`%s`, `.+`)
+	if assert.Error(t, err) {
+		assert.Equal(t, `wildcard "%s" is already registered`, err.Error())
+	}
+	err = RegisterWildcard(`%invalid`, `(`)
+	if assert.Error(t, err) {
+		assert.Equal(t, "wildcard \"%invalid\" has invalid regexp \"(\": error parsing regexp: missing closing ): `(`", err.Error())
+	}
+	for _, token := range []string{`%3x`, `%2,4abc`, `%{id:d}`, `%{x`, `%x{`} {
+		err = RegisterWildcard(token, `.+`)
+		if assert.Error(t, err) {
+			assert.Equal(t, fmt.Sprintf(`wildcard "%s" conflicts with the count or named capture syntax`, token), err.Error())
+		}
+	}
+}
+
+func TestAssertFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "expected.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("Foo1: %s\nFoo2: %d\n"), 0o600))
+
+	// Match
+	test := &mockedT{buf: bytes.NewBuffer(nil)}
+	assert.True(t, AssertFile(test, path, "Foo1: bar\nFoo2: 123"))
+	assert.Equal(t, "", test.buf.String())
+
+	// Mismatch
+	test = &mockedT{buf: bytes.NewBuffer(nil)}
+	assert.False(t, AssertFile(test, path, "Foo1: bar\nFoo2: abc"))
+	assert.Contains(t, test.buf.String(), "-Foo2:␣%d\n")
+	assert.Contains(t, test.buf.String(), "+Foo2:␣abc\n")
+
+	// Missing file
+	test = &mockedT{buf: bytes.NewBuffer(nil)}
+	missingPath := filepath.Join(t.TempDir(), "missing.txt")
+	assert.False(t, AssertFile(test, missingPath, "foo"))
+	assert.Contains(t, test.buf.String(), fmt.Sprintf(`cannot read expected file "%s"`, missingPath))
+}
+
+func TestMatchCapture(t *testing.T) {
+	t.Parallel()
+
+	// One capture
+	values, ok := MatchCapture(`id: %{id:d}`, `id: 123`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"id": "123"}, values)
+
+	// Multiple captures
+	values, ok = MatchCapture(`%{name:s} (%{id:d}), %{hash:x}%%`, `foo bar (456), 0af%`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "foo bar", "id": "456", "hash": "0af"}, values)
+
+	// No capture
+	values, ok = MatchCapture(`id: %d`, `id: 123`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{}, values)
+
+	// No match
+	values, ok = MatchCapture(`id: %{id:d}`, `id: abc`)
+	assert.False(t, ok)
+	assert.Nil(t, values)
+
+	// Regexp
+	assert.Equal(t, `(?P<id>\d+)`, ToRegexp(`%{id:d}`))
+	assert.Equal(t, `%\{id:unknown\}`, ToRegexp(`%{id:unknown}`))
+	assert.Equal(t, `%\{1id:d\}`, ToRegexp(`%{1id:d}`))
+}
+
+func TestJSONWildcard(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{pattern: `%json`, input: `{}`, match: true},
+		{pattern: `%json`, input: `[1, "a", {"b": null}]`, match: true},
+		{pattern: `%json`, input: "{\n  \"a\": 1\n}", match: true},
+		{pattern: `%json`, input: `{"a": 1,}`, match: false},
+		{pattern: `%json`, input: `{"a": }`, match: false},
+		{pattern: `%json`, input: `123`, match: false},
+		{pattern: `level=info data=%json end`, input: `level=info data={"id": 123, "tags": ["a"]} end`, match: true},
+		{pattern: `level=info data=%json end`, input: `level=info data={"id": 123, "tags": ["a"} end`, match: false},
+		{pattern: `%json and %json`, input: `{"a": 1} and [2]`, match: true},
+		{pattern: `%json and %json`, input: `{"a": 1} and [2,]`, match: false},
+		{pattern: `%json %json`, input: `{"a": 1} {"b": "}"}`, match: true},
+		{pattern: `data=%json}`, input: `data={"a": [1, {"b": 2}]}}`, match: true},
+		{pattern: `data=%json end}`, input: `data={"a": 1} end}`, match: true},
+		{pattern: `data=%json end}`, input: `data={"a": 1 end}`, match: false},
+	}
+
+	for _, data := range cases {
+		desc := fmt.Sprintf(`pattern: "%s", input: "%s"`, data.pattern, data.input)
+		assert.Equal(t, data.match, Match(data.pattern, data.input), desc)
+		assert.Equal(t, data.match, Compare(data.pattern, data.input) == nil, desc)
+	}
+
+	// Named capture
+	values, ok := MatchCapture(`data: %{data:json}`, `data: {"id": 123}`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"data": `{"id": 123}`}, values)
+	_, ok = MatchCapture(`data: %{data:json}`, `data: {"id": }`)
+	assert.False(t, ok)
+
+	// Contains
+	assert.True(t, MatchContains(`data=%json`, `level=info data={"id": 123}`))
+	assert.False(t, MatchContains(`data=%json`, `level=info data={"id": }`))
+	assert.True(t, MatchContains(`%json`, `log: {"a":1} end {"b":2}`))
+	assert.True(t, MatchContains(`%json end`, `log: {"a":1} end {"b":2}`))
+	assert.True(t, MatchContains(`data=%json`, `data={"a":1} end}`))
+
+	// Two blobs on one line are captured separately
+	values, ok = MatchCapture(`log: %{first:json} end %{second:json}`, `log: {"a":1} end {"b":2}`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"first": `{"a":1}`, "second": `{"b":2}`}, values)
+
+	// Multiple lines, the block with valid JSON is omitted from the diff
+	expected := "foo\ndata: %json\nbar"
+	assert.Equal(t, "", CleanDiff(expected, "foo\ndata: {\"id\": 123}\nbar"))
+	assert.NotEqual(t, "", CleanDiff(expected, "foo\ndata: {\"id\" 123}\nbar"))
+}
+
+func TestMatchContains(t *testing.T) {
+	t.Parallel()
+	assert.True(t, MatchContains(`id: %d,`, `foo, id: 123, bar`))
+	assert.True(t, MatchContains(`%d`, `foo 123 bar`))
+	assert.True(t, MatchContains(``, `foo`))
+	assert.False(t, MatchContains(`id: %d,`, `foo, id: abc, bar`))
+	assert.False(t, Match(`id: %d,`, `foo, id: 123, bar`))
+}
+
+func TestAssertContains(t *testing.T) {
+	t.Parallel()
+
+	// Contains
+	test := &mockedT{buf: bytes.NewBuffer(nil)}
+	ok := AssertContains(test, "Foo2: %s\nFoo3: %d", "Foo1: bar1\nFoo2: bar2\nFoo3: 3\nFoo4: bar4")
+	assert.True(t, ok)
+	assert.Equal(t, "", test.buf.String())
+
+	// Not contains
+	test = &mockedT{buf: bytes.NewBuffer(nil)}
+	ok = AssertContains(test, "Foo2: %s\nFoo3: %d", "Foo1: bar1\nFoo2: bar2\nFoo3: bar3")
+	assert.False(t, ok)
+	expected := `
+Actual:
+-----
+Foo1: bar1
+Foo2: bar2
+Foo3: bar3
+-----
+Expected to contain:
+-----
+Foo2: %s
+Foo3: %d
+-----
+`
+	// Get error message
+	_, testLog, _ := strings.Cut(test.buf.String(), "Error:")
+	// Trim leading whitespaces from each line
+	testLog = regexp.MustCompile(`(?m)^\s+`).ReplaceAllString(testLog, "")
+	// Compare
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(testLog))
+}
+
+func TestWildcardCount(t *testing.T) {
+	t.Parallel()
+
+	// Regexp
+	assert.Equal(t, `\d{3}`, ToRegexp(`%3d`))
+	assert.Equal(t, `.{2,4}`, ToRegexp(`%2,4s`))
+	assert.Equal(t, `(.|\n){1,2}`, ToRegexp(`%1,2a`))
+	assert.Equal(t, `(?P<year>\d{4})`, ToRegexp(`%{year:4d}`))
+	assert.Equal(t, `\d+`, ToRegexp(`%d`))
+	assert.Equal(t, `%3i`, ToRegexp(`%3i`))       // not repeatable
+	assert.Equal(t, `%4,2s`, ToRegexp(`%4,2s`))   // invalid range
+	assert.Equal(t, `%1001d`, ToRegexp(`%1001d`)) // too large
+
+	cases := []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{pattern: `%3d`, input: `12`, match: false},
+		{pattern: `%3d`, input: `123`, match: true},
+		{pattern: `%3d`, input: `1234`, match: false},
+		{pattern: `%3d`, input: `12a`, match: false},
+		{pattern: `%2,4s`, input: `a`, match: false},
+		{pattern: `%2,4s`, input: `ab`, match: true},
+		{pattern: `%2,4s`, input: `abcd`, match: true},
+		{pattern: `%2,4s`, input: `abcde`, match: false},
+		{pattern: `%2,4s`, input: "a\nb", match: false},
+		{pattern: `%2x-%2x`, input: `0a-fF`, match: true},
+		{pattern: `%2x-%2x`, input: `0a-fg`, match: false},
+		{pattern: `%0,1c`, input: ``, match: true},
+		{pattern: `%%3d`, input: `%3d`, match: true},
+	}
+
+	for _, data := range cases {
+		assert.Equal(t, data.match, Match(data.pattern, data.input), fmt.Sprintf(`pattern: "%s", input: "%s"`, data.pattern, data.input))
+	}
+
+	// Named capture with count
+	values, ok := MatchCapture(`%{year:4d}-%{month:2d}`, `2022-01`)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"year": "2022", "month": "01"}, values)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	wildcards := `
+Foo1: bar1
+Foo2: %s
+Foo3: bar3
+Foo4: %s
+Foo5: bar5
+Foo6: %c%c%c
+Foo7: bar7
+`
+
+	actual := `
+Foo1: bar1
+Foo2:
+Foo3: bar3
+Foo4: bar4
+Foo5: bar5
+Foo6: abcdef
+Foo7: bar7
+`
+
+	diff, match := Diff(wildcards, actual)
+	assert.False(t, match)
+	assert.Equal(t, []LineDiff{
+		{ExpectedLine: 2, ActualLine: 2, Expected: []string{"Foo2: %s"}, Actual: []string{"Foo2:"}, Match: false},
+		{ExpectedLine: 4, ActualLine: 4, Expected: []string{"Foo4: %s"}, Actual: []string{"Foo4: bar4"}, Match: true},
+		{ExpectedLine: 6, ActualLine: 6, Expected: []string{"Foo6: %c%c%c"}, Actual: []string{"Foo6: abcdef"}, Match: false},
+	}, diff)
+}
+
+func TestDiff_Match(t *testing.T) {
+	t.Parallel()
+	diff, match := Diff("Foo1: %d\nFoo2: bar2", "Foo1: 123\nFoo2: bar2")
+	assert.True(t, match)
+	assert.Equal(t, []LineDiff{
+		{ExpectedLine: 1, ActualLine: 1, Expected: []string{"Foo1: %d"}, Actual: []string{"Foo1: 123"}, Match: true},
+	}, diff)
+
+	diff, match = Diff("foo", "foo")
+	assert.True(t, match)
+	assert.Empty(t, diff)
+}
+
+func TestDiff_MissingLines(t *testing.T) {
+	t.Parallel()
+	diff, match := Diff("Foo1\nFoo2\nFoo3\nFoo4", "Foo1\nFoo4\nFoo5")
+	assert.False(t, match)
+	assert.Equal(t, []LineDiff{
+		{ExpectedLine: 2, ActualLine: 1, Expected: []string{"Foo2", "Foo3"}, Actual: []string{}, Match: false},
+		{ExpectedLine: 4, ActualLine: 3, Expected: []string{}, Actual: []string{"Foo5"}, Match: false},
+	}, diff)
+}
+
+func TestCleanDiff(t *testing.T) {
+	t.Parallel()
+	wildcards := `
+Foo1: bar1
+Foo2: %s
+Foo3: bar3
+Foo4: %s
+Foo5: bar5
+`
+
+	actual := `
+Foo1: bar1
+Foo2:
+Foo3: bar3
+Foo4: bar4
+Foo5: bar5
+`
+
+	// Block with "Foo4" matches the wildcard, so it is omitted
+	expected := `
+@@ -2 +2 @@
+-Foo2:␣%s
++Foo2:
+`
+	assert.Equal(t, strings.TrimLeft(expected, "\n"), CleanDiff(wildcards, actual))
+
+	// No difference
+	assert.Equal(t, "", CleanDiff("Foo: %d", "Foo: 123"))
+}
+
+func TestAssertWithOptions_MaxDiffLines(t *testing.T) {
+	t.Parallel()
+	var expected, actual []string
+	for i := 1; i <= 20; i++ {
+		// Every second line is different
+		if i%2 == 0 {
+			expected = append(expected, fmt.Sprintf("Foo%d: %%d", i))
+			actual = append(actual, fmt.Sprintf("Foo%d: bar", i))
+		} else {
+			expected = append(expected, fmt.Sprintf("Foo%d: ok", i))
+			actual = append(actual, fmt.Sprintf("Foo%d: ok", i))
+		}
+	}
+
+	test := &mockedT{buf: bytes.NewBuffer(nil)}
+	ok := AssertWithOptions(test, strings.Join(expected, "\n"), strings.Join(actual, "\n"), Options{MaxDiffLines: 2})
+	assert.False(t, ok)
+	expectedDiff := `
+Diff:
+-----
+@@ -2 +2 @@
+-Foo2:␣%d
++Foo2:␣bar
+@@ -4 +4 @@
+-Foo4:␣%d
++Foo4:␣bar
+... and 8 more differences
+-----
+`
+	// Get error message
+	_, testLog, _ := strings.Cut(test.buf.String(), "Error:")
+	// Trim leading whitespaces from each line
+	testLog = regexp.MustCompile(`(?m)^\s+`).ReplaceAllString(testLog, "")
+	assert.True(t, strings.HasPrefix(testLog, strings.TrimLeft(expectedDiff, "\n")), testLog)
+
+	// The texts are compared as a whole
+	assert.True(t, AssertWithOptions(t, "Foo: %d\nBar: %s", "Foo: 1\nBar: x", Options{MaxDiffLines: 1}))
+	assert.Error(t, CompareWithOptions("Foo: %d\nBar", "Foo: 1\nBaz", Options{MaxDiffLines: 1}))
+}